@@ -0,0 +1,104 @@
+/*
+Copyright © 2023 Charles Corbett <github.com/asciifaceman>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/clio"
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// bufferbloatCmd represents the bufferbloat command
+var bufferbloatCmd = &cobra.Command{
+	Use:   "bufferbloat",
+	Short: "Measure how much latency increases under load",
+	Long: `Measure latency to the ping targets while idle, then again while
+the link is saturated in each direction, and grade the increase.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := newSpeedTest()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		pinger, err := newStatus()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer pinger.Stop()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		fmt.Printf("Pinging %v idle and under load with %d streams...\n", pinger.PingHosts, speedTestStreams)
+		report := s.Bufferbloat(ctx, pinger)
+
+		p := clio.NewPrinter(40, 25, 2)
+		p.PrintHeader(fmt.Sprintf("Gomo %s", version))
+		printBufferbloat(p, report)
+	},
+}
+
+// printBufferbloat prints the results of a bufferbloat test
+func printBufferbloat(p *clio.Printer, report *models.BufferbloatReport) {
+	if report.Error != "" {
+		p.PrintHeader("Failed to test bufferbloat")
+		p.PrintKVIndent("Error", report.Error)
+		return
+	}
+
+	p.PrintHeader("Idle")
+	p.PrintKVIndent("Latency", fmt.Sprintf("%s avg / %s max",
+		report.Idle.Avg.Round(time.Microsecond),
+		report.Idle.Max.Round(time.Microsecond),
+	))
+
+	for _, loaded := range []*models.LoadedLatencyReport{report.Download, report.Upload} {
+		if loaded == nil {
+			continue
+		}
+		p.PrintHeader(loaded.Direction)
+		p.PrintKVIndent("Latency", fmt.Sprintf("%s avg / %s max",
+			loaded.Latency.Avg.Round(time.Microsecond),
+			loaded.Latency.Max.Round(time.Microsecond),
+		))
+		p.PrintKVIndent("Increase", loaded.Increase.Round(time.Microsecond))
+		p.PrintKVIndent("Grade", loaded.Grade)
+		if loaded.Throughput != nil {
+			p.PrintKVIndent("Throughput", fmt.Sprintf("%.2f Mbps", loaded.Throughput.BitsPerSecond/1e6))
+			if loaded.Throughput.Error != "" {
+				p.PrintKVIndent("Error", loaded.Throughput.Error)
+			}
+		}
+	}
+
+	p.PrintHeader("Result")
+	p.PrintKVIndent("Grade", report.Grade)
+}
+
+func init() {
+	rootCmd.AddCommand(bufferbloatCmd)
+
+	addSpeedTestFlags(bufferbloatCmd.Flags())
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2023 Charles Corbett <github.com/asciifaceman>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/cells"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cellsFile       = "cells.json"
+	cellsTimeFormat = "2006-01-02 15:04:05"
+)
+
+var (
+	cellsSort    = "last_seen"
+	cellsReverse = false
+	cellsRadio   = ""
+)
+
+// cellsCmd represents the cells command
+var cellsCmd = &cobra.Command{
+	Use:   "cells",
+	Short: "List every cell the trashcan has attached to",
+	Long: `List every PCI/band combination the trashcan has attached to
+along with when it was seen, how long it was attached and the best, median
+and worst RSRP/SNR observed on it. History is recorded by the daemon.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		h, err := cells.Load(dataPath(cellsFile))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		list := h.List()
+		if cellsRadio != "" {
+			filtered := list[:0]
+			for _, c := range list {
+				if strings.EqualFold(c.Spectrum, cellsRadio) {
+					filtered = append(filtered, c)
+				}
+			}
+			list = filtered
+		}
+
+		if err := cells.Sort(list, cellsSort, cellsReverse); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if len(list) == 0 {
+			fmt.Println("No cells recorded yet, run the daemon to build history")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RADIO\tBAND\tPCI\tFIRST SEEN\tLAST SEEN\tATTACHED\tSAMPLES\tRSRP best/med/worst\tSNR best/med/worst")
+		for _, c := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%.0f/%.1f/%.0f\t%.0f/%.1f/%.0f\n",
+				c.Spectrum,
+				c.Band,
+				c.PCI,
+				c.FirstSeen.Local().Format(cellsTimeFormat),
+				c.LastSeen.Local().Format(cellsTimeFormat),
+				c.Attached.Round(time.Second),
+				c.Samples,
+				c.RSRP.Best(), c.RSRP.Median(), c.RSRP.Worst(),
+				c.SNR.Best(), c.SNR.Median(), c.SNR.Worst(),
+			)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cellsCmd)
+
+	cellsCmd.Flags().StringVar(&cellsSort, "sort", cellsSort, "Sort by one of last_seen, first_seen, attached, samples, rsrp, snr, band")
+	cellsCmd.Flags().BoolVar(&cellsReverse, "reverse", cellsReverse, "Reverse the sort order")
+	cellsCmd.Flags().StringVar(&cellsRadio, "radio", cellsRadio, "Only list cells for one radio (5G or LTE)")
+}
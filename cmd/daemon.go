@@ -5,13 +5,55 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/asciifaceman/gomo/pkg/cells"
 	"github.com/asciifaceman/gomo/pkg/clients"
+	"github.com/asciifaceman/gomo/pkg/metrics"
+	"github.com/asciifaceman/gomo/pkg/outage"
+	"github.com/asciifaceman/gomo/pkg/push"
+	"github.com/asciifaceman/gomo/pkg/slo"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 )
 
 var (
-	serverPort = 2112
+	serverPort          = 2112
+	speedTestInterval   = 0
+	bufferbloatInterval = 0
+	discoveryInterval   = int(clients.DefaultDiscoveryInterval / time.Minute)
+	outageThreshold     = outage.DefaultThreshold
+	listenAddress       = ""
+	metricsPath         = clients.DefaultMetricsPath
+	metricGroups        []string
+	runtimeMetrics      = false
+	bandLabels          = false
+	legacyMetricNames   = false
+	staleAfter          = clients.DefaultStaleAfter
+	onDemand            = false
+	cacheTTL            = int(clients.DefaultCacheTTL / time.Second)
+	pollInterval        = int(clients.DefaultPollInterval / time.Second)
+	adaptivePoll        = false
+	pollMin             = int(clients.DefaultPollMin / time.Second)
+	pollMax             = int(clients.DefaultPollMax / time.Second)
+	pushURL             = ""
+	pushMode            = push.ModeRemoteWrite
+	pushInterval        = int(push.DefaultInterval / time.Second)
+	pushJob             = push.DefaultJob
+	pushLabels          map[string]string
+	pushBatchSize       = push.DefaultBatchSize
+	pushBufferSize      = push.DefaultBufferSize >> 20
+	pushOrg             = ""
+	pushBucket          = ""
+	pushToken           = ""
+)
+
+const (
+	pushBufferDir   = "push"
+	influxBufferDir = "influx"
 )
 
 // daemonCmd represents the daemon command
@@ -28,6 +70,96 @@ historical analysis.`,
 			return
 		}
 
+		if listenAddress != "" {
+			d.Server.Addr = listenAddress
+		}
+		if !strings.HasPrefix(metricsPath, "/") {
+			fmt.Printf("Metrics path %q must start with /\n", metricsPath)
+			return
+		}
+		d.MetricsPath = metricsPath
+		for _, group := range metricGroups {
+			if !knownMetricGroup(group) {
+				fmt.Printf("Unknown metric group %q, expected one of %s\n", group, strings.Join(metrics.Groups, ", "))
+				return
+			}
+		}
+		d.MetricGroups = metricGroups
+		d.RuntimeMetrics = runtimeMetrics
+		d.BandLabels = bandLabels
+		d.LegacyMetricNames = legacyMetricNames
+		d.StaleAfter = staleAfter
+		d.OnDemand = onDemand
+		d.CacheTTL = time.Duration(cacheTTL) * time.Second
+
+		if pollInterval <= 0 || pollMin <= 0 {
+			fmt.Println("Poll intervals must be at least 1 second")
+			return
+		}
+		d.PollInterval = time.Duration(pollInterval) * time.Second
+		if adaptivePoll {
+			d.AdaptivePoll = clients.NewAdaptivePoll(d.PollInterval, time.Duration(pollMin)*time.Second, time.Duration(pollMax)*time.Second)
+		}
+
+		d.CellHistory, err = cells.Load(dataPath(cellsFile))
+		if err != nil {
+			fmt.Printf("Failed to load cell history: %v\n", err)
+			return
+		}
+
+		d.Outages, err = outage.Load(dataPath(outagesFile))
+		if err != nil {
+			fmt.Printf("Failed to load outage log: %v\n", err)
+			return
+		}
+		d.Outages.Threshold = outageThreshold
+
+		if statusEnabled() {
+			d.Status, err = newStatus()
+			if err != nil {
+				fmt.Printf("Failed to setup status checks: %v\n", err)
+				return
+			}
+
+			if discoveryInterval < 0 {
+				fmt.Println("Discovery interval can't be negative")
+				return
+			}
+			d.DiscoveryInterval = time.Duration(discoveryInterval) * time.Minute
+
+			objectives, err := loadSLOs()
+			if err != nil {
+				fmt.Printf("Failed to setup SLOs: %v\n", err)
+				return
+			}
+			if len(objectives) > 0 {
+				d.SLOs, err = slo.Load(dataPath(sloFile), objectives)
+				if err != nil {
+					fmt.Printf("Failed to load SLO history: %v\n", err)
+					return
+				}
+			}
+		}
+
+		if speedTestInterval > 0 || bufferbloatInterval > 0 {
+			d.SpeedTest, err = newSpeedTest()
+			if err != nil {
+				fmt.Printf("Failed to setup speed test: %v\n", err)
+				return
+			}
+			d.SpeedTestInterval = time.Duration(speedTestInterval) * time.Minute
+			d.BufferbloatInterval = time.Duration(bufferbloatInterval) * time.Minute
+		}
+
+		if pushURL != "" {
+			d.Push, err = newPushSink(d.Registry, time.Duration(reqtimeout)*time.Second)
+			if err != nil {
+				fmt.Printf("Failed to setup push: %v\n", err)
+				return
+			}
+			d.PushInterval = time.Duration(pushInterval) * time.Second
+		}
+
 		err = d.Run()
 		if err != nil {
 			d.Logger.Errorw("Runtime error", "error", err)
@@ -43,6 +175,34 @@ func init() {
 	rootCmd.AddCommand(daemonCmd)
 
 	daemonCmd.PersistentFlags().IntVarP(&serverPort, "port", "m", serverPort, "Port to bind metrics webserver to")
+	daemonCmd.PersistentFlags().StringVar(&listenAddress, "listen", listenAddress, "Address to bind metrics webserver to, ex. 127.0.0.1:2112, overrides --port")
+	daemonCmd.PersistentFlags().StringVar(&metricsPath, "metrics-path", metricsPath, "Path to serve prometheus metrics on")
+	daemonCmd.PersistentFlags().StringSliceVar(&metricGroups, "metric-groups", metricGroups, "Metric groups to export, empty for all: "+strings.Join(metrics.Groups, ","))
+	daemonCmd.PersistentFlags().BoolVar(&bandLabels, "band-labels", bandLabels, "Label the SNR, RSRP, RSRQ and RSSI gauges with the band and PCI of the attached cell")
+	daemonCmd.PersistentFlags().BoolVar(&legacyMetricNames, "legacy-metric-names", legacyMetricNames, "Also export the trashcan metrics under their names from before gomo followed the prometheus naming conventions")
+	daemonCmd.PersistentFlags().IntVar(&staleAfter, "stale-after", staleAfter, "Failed scrapes in a row after which the radio metrics are cleared, 0 keeps the last values")
+	daemonCmd.PersistentFlags().IntVar(&pollInterval, "poll-interval", pollInterval, "Seconds between polls of the trashcan and status checks")
+	daemonCmd.PersistentFlags().BoolVar(&adaptivePoll, "adaptive-poll", adaptivePoll, "Poll faster during outages, handovers and fast signal changes and back off while the trashcan is slow")
+	daemonCmd.PersistentFlags().IntVar(&pollMin, "poll-min", pollMin, "Fastest seconds between polls with --adaptive-poll")
+	daemonCmd.PersistentFlags().IntVar(&pollMax, "poll-max", pollMax, "Slowest seconds between polls with --adaptive-poll")
+	daemonCmd.PersistentFlags().BoolVar(&onDemand, "on-demand", onDemand, "Fetch from the trashcan when prometheus scrapes instead of polling it")
+	daemonCmd.PersistentFlags().IntVar(&cacheTTL, "cache-ttl", cacheTTL, "Seconds an on demand fetch is reused for across scrapes")
+	daemonCmd.PersistentFlags().BoolVar(&runtimeMetrics, "runtime-metrics", runtimeMetrics, "Export Go runtime and process metrics of gomo itself")
+	daemonCmd.PersistentFlags().IntVar(&speedTestInterval, "speedtest-interval", speedTestInterval, "Minutes between scheduled speed tests, 0 disables them")
+	daemonCmd.PersistentFlags().IntVar(&bufferbloatInterval, "bufferbloat-interval", bufferbloatInterval, "Minutes between scheduled bufferbloat tests, 0 disables them")
+	daemonCmd.PersistentFlags().IntVar(&discoveryInterval, "discovery-interval", discoveryInterval, "Minutes between path MTU, CGNAT and NAT type checks, which rarely change, 0 disables them")
+	daemonCmd.PersistentFlags().IntVar(&outageThreshold, "outage-threshold", outageThreshold, "Consecutive scrapes that must agree before an outage is opened or closed")
+	daemonCmd.PersistentFlags().StringVar(&pushURL, "push-url", pushURL, "Pushgateway or remote write URL to push metrics to, for gateways prometheus can't scrape")
+	daemonCmd.PersistentFlags().StringVar(&pushMode, "push-mode", pushMode, "How to push metrics to --push-url: "+strings.Join(push.Modes, ", "))
+	daemonCmd.PersistentFlags().IntVar(&pushInterval, "push-interval", pushInterval, "Seconds between pushes")
+	daemonCmd.PersistentFlags().StringVar(&pushJob, "push-job", pushJob, "Job label of pushed metrics")
+	daemonCmd.PersistentFlags().StringToStringVar(&pushLabels, "push-labels", pushLabels, "Labels added to pushed metrics, ex. site=cabin, instance defaults to the hostname")
+	daemonCmd.PersistentFlags().IntVar(&pushBatchSize, "push-batch-size", pushBatchSize, "Most samples sent in one remote write or InfluxDB request")
+	daemonCmd.PersistentFlags().IntVar(&pushBufferSize, "push-buffer-size", pushBufferSize, "MiB of unsent remote write or InfluxDB samples kept on disk while the endpoint is unreachable")
+	daemonCmd.PersistentFlags().StringVar(&pushOrg, "push-org", pushOrg, "InfluxDB organization to write to with --push-mode influx")
+	daemonCmd.PersistentFlags().StringVar(&pushBucket, "push-bucket", pushBucket, "InfluxDB bucket to write to with --push-mode influx")
+	daemonCmd.PersistentFlags().StringVar(&pushToken, "push-token", pushToken, "InfluxDB API token, defaults to $INFLUX_TOKEN")
+	addSpeedTestFlags(daemonCmd.PersistentFlags())
 
 	// Here you will define your flags and configuration settings.
 
@@ -54,3 +214,71 @@ func init() {
 	// is called directly, e.g.:
 	// daemonCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// knownMetricGroup returns true if group is one of metrics.Groups
+func knownMetricGroup(group string) bool {
+	for _, g := range metrics.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// newPushSink returns the push sink configured by the push flags, shipping
+// the metrics gathered from g
+func newPushSink(g prometheus.Gatherer, timeout time.Duration) (push.Sink, error) {
+	if pushInterval <= 0 {
+		return nil, fmt.Errorf("push interval must be at least 1 second")
+	}
+
+	// InfluxDB points are tagged with the gateway they were sampled from
+	instance := "instance"
+	if pushMode == push.ModeInflux {
+		instance = "gateway"
+	}
+	labels := make(map[string]string, len(pushLabels)+1)
+	for name, value := range pushLabels {
+		labels[name] = value
+	}
+	if _, ok := labels[instance]; !ok {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		labels[instance] = host
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	switch pushMode {
+	case push.ModePushgateway:
+		return push.NewGateway(pushURL, pushJob, labels, g, client), nil
+	case push.ModeRemoteWrite:
+		buffer, err := push.OpenBuffer(dataPath(pushBufferDir), int64(pushBufferSize)<<20)
+		if err != nil {
+			return nil, err
+		}
+		labels["job"] = pushJob
+		w := push.NewRemoteWriter(pushURL, labels, g, buffer, client)
+		w.BatchSize = pushBatchSize
+		return w, nil
+	case push.ModeInflux:
+		token := pushToken
+		if token == "" {
+			token = os.Getenv("INFLUX_TOKEN")
+		}
+		buffer, err := push.OpenBuffer(dataPath(influxBufferDir), int64(pushBufferSize)<<20)
+		if err != nil {
+			return nil, err
+		}
+		w, err := push.NewInflux(pushURL, pushOrg, pushBucket, token, labels, buffer, client)
+		if err != nil {
+			return nil, err
+		}
+		w.BatchSize = pushBatchSize
+		return w, nil
+	default:
+		return nil, fmt.Errorf("unknown push mode %q, expected one of %s", pushMode, strings.Join(push.Modes, ", "))
+	}
+}
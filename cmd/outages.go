@@ -0,0 +1,113 @@
+/*
+Copyright © 2023 Charles Corbett <github.com/asciifaceman>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/clio"
+	"github.com/asciifaceman/gomo/pkg/outage"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outagesFile = "outages.json"
+)
+
+var (
+	outagesDays = 30
+)
+
+// outagesCmd represents the outages command
+var outagesCmd = &cobra.Command{
+	Use:   "outages",
+	Short: "List recorded outages and uptime",
+	Long: `List every outage the daemon has recorded along with its
+classification, start, end and duration, and summarize uptime over
+the window. Time the daemon was not running is reported as
+unmonitored and counts as neither uptime nor downtime. Outages are
+classified as gateway_unreachable when the trashcan could not be
+scraped, wan_down when it reports no WAN connection and
+internet_unreachable when every ping, TCP and HTTP check failed
+while the WAN was connected.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		d, err := outage.Load(dataPath(outagesFile))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if d.Since.IsZero() {
+			fmt.Println("No outage history recorded yet, run the daemon to build history")
+			return
+		}
+
+		now := time.Now()
+		since := d.Since
+		if outagesDays > 0 {
+			since = now.AddDate(0, 0, -outagesDays)
+		}
+		summary := d.Summarize(since, now)
+
+		p := clio.NewPrinter(40, 25, 2)
+		p.PrintHeader("Outages")
+		p.PrintKVIndent("Window", summary.Window.Round(time.Second).String())
+		p.PrintKVIndent("Monitored", summary.Monitored.Round(time.Second).String())
+		p.PrintKVIndent("Unmonitored", summary.Unmonitored.Round(time.Second).String())
+		p.PrintKVIndent("Outages", summary.Count)
+		for _, state := range outage.States {
+			if count, ok := summary.ByClassification[state]; ok {
+				p.PrintKVIndent(state, count)
+			}
+		}
+		p.PrintKVIndent("Downtime", summary.Downtime.Round(time.Second).String())
+		p.PrintKVIndent("Uptime", fmt.Sprintf("%.3f%%", summary.Uptime*100))
+
+		if summary.Count == 0 {
+			return
+		}
+
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CLASSIFICATION\tSTART\tEND\tDURATION\tREASON")
+		for _, o := range d.List() {
+			if o.End != nil && o.End.Before(since) {
+				continue
+			}
+			end := "ongoing"
+			if o.End != nil {
+				end = o.End.Local().Format(cellsTimeFormat)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				o.Classification,
+				o.Start.Local().Format(cellsTimeFormat),
+				end,
+				o.Duration(now).Round(time.Second),
+				o.Reason,
+			)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outagesCmd)
+
+	outagesCmd.Flags().IntVar(&outagesDays, "days", outagesDays, "Only include outages from the last N days, 0 for all history")
+}
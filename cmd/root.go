@@ -16,8 +16,11 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/asciifaceman/gomo/pkg/status"
 	"github.com/spf13/cobra"
@@ -30,6 +33,25 @@ var hostname string
 var pingtargets []string
 var reqtimeout int
 var pingWorkerCount int
+var pingCount int
+var pingMode string
+var gatewayProbe bool
+var dualStack bool
+var dataDir string
+var dnsNames []string
+var dnsResolvers []string
+var dnsCount int
+var tcpTargets []string
+var tcpTimeout int
+var httpTargets []string
+var httpTimeout int
+var httpExpect []int
+var mtuTargets []string
+var echoURL string
+var stunServers []string
+var wans []string
+var wanTargets []string
+var wanTCPTargets []string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -113,6 +135,25 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&reqtimeout, "timeout", "s", 15, "timeout in seconds for outbound requests")
 	rootCmd.PersistentFlags().StringSliceVarP(&pingtargets, "targets", "p", status.DefaultPingHosts, "List of hostnames to target with ping test")
 	rootCmd.PersistentFlags().IntVarP(&pingWorkerCount, "workers", "w", status.DefaultWorkerCount, "number of workers for pingers")
+	rootCmd.PersistentFlags().IntVar(&pingCount, "ping-count", status.DefaultPingCount, "number of pings to send to each target per check")
+	rootCmd.PersistentFlags().StringVar(&pingMode, "ping-mode", status.DefaultPingMode, "ICMP socket to ping with: auto, privileged (raw) or unprivileged (udp)")
+	rootCmd.PersistentFlags().BoolVar(&gatewayProbe, "gateway-probe", true, "ping the trashcan itself to separate local network trouble from WAN trouble")
+	rootCmd.PersistentFlags().BoolVar(&dualStack, "dual-stack", false, "run every ping, DNS and TCP check over IPv4 and IPv6 separately and flag a broken family")
+	rootCmd.PersistentFlags().StringSliceVar(&dnsNames, "dns-names", status.DefaultDNSNames, "List of names to resolve with DNS test")
+	rootCmd.PersistentFlags().StringSliceVar(&dnsResolvers, "resolvers", status.DefaultDNSResolvers, "List of DNS resolvers to compare against the trashcan's resolver, IPv6 resolvers are added with --dual-stack unless set")
+	rootCmd.PersistentFlags().IntVar(&dnsCount, "dns-count", status.DefaultDNSCount, "number of lookups of each name per resolver per check")
+	rootCmd.PersistentFlags().StringSliceVar(&tcpTargets, "tcp-targets", status.DefaultTCPTargets, "List of host:port targets to test with TCP connect")
+	rootCmd.PersistentFlags().IntVar(&tcpTimeout, "tcp-timeout", int(status.DefaultTCPTimeout.Seconds()), "timeout in seconds for each TCP connect check")
+	rootCmd.PersistentFlags().StringSliceVar(&httpTargets, "http-targets", status.DefaultHTTPTargets, "List of URLs to test with HTTP GET")
+	rootCmd.PersistentFlags().IntVar(&httpTimeout, "http-timeout", int(status.DefaultHTTPTimeout.Seconds()), "timeout in seconds for each HTTP check")
+	rootCmd.PersistentFlags().IntSliceVar(&httpExpect, "http-expect", status.DefaultHTTPExpectedCodes, "List of HTTP status codes considered healthy")
+	rootCmd.PersistentFlags().StringSliceVar(&mtuTargets, "mtu-targets", status.DefaultMTUTargets, "List of hosts to discover the path MTU to with don't fragment pings")
+	rootCmd.PersistentFlags().StringVar(&echoURL, "echo-url", status.DefaultEchoURL, "URL which returns the caller's public IPv4 address, used to detect CGNAT. empty disables")
+	rootCmd.PersistentFlags().StringSliceVar(&stunServers, "stun-servers", status.DefaultSTUNServers, "List of host:port STUN servers to discover the NAT type with, the first supporting RFC 5780 is used for behavior tests")
+	rootCmd.PersistentFlags().StringSliceVar(&wans, "wans", nil, "List of name=address or name=interface uplinks to probe side by side, ex. cellular=wlan0,backup=eth1")
+	rootCmd.PersistentFlags().StringSliceVar(&wanTargets, "wan-targets", nil, "List of name=host ping targets per WAN, WANs without any use --targets")
+	rootCmd.PersistentFlags().StringSliceVar(&wanTCPTargets, "wan-tcp-targets", nil, "List of name=host:port TCP targets per WAN, WANs without any use --tcp-targets")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "directory for persisted gomo data (default is $HOME/.gomo)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -142,3 +183,135 @@ func initConfig() {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// dataPath returns the path of a file within the gomo data directory
+func dataPath(name string) string {
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		cobra.CheckErr(err)
+		dataDir = filepath.Join(home, ".gomo")
+	}
+	return filepath.Join(dataDir, name)
+}
+
+// statusEnabled returns true if any status check is configured
+func statusEnabled() bool {
+	return len(pingtargets) > 0 || gatewayProbe || len(dnsNames) > 0 || len(tcpTargets) > 0 ||
+		len(httpTargets) > 0 || len(mtuTargets) > 0 || echoURL != "" || len(stunServers) > 0 || len(wans) > 0
+}
+
+// newWANs builds the WANs to compare from the WAN flags. WANs without their own
+// targets use the global ping and TCP targets
+func newWANs() ([]status.WAN, error) {
+	pingGroups, err := status.GroupTargets(wanTargets)
+	if err != nil {
+		return nil, err
+	}
+	tcpGroups, err := status.GroupTargets(wanTCPTargets)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]status.WAN, 0, len(wans))
+	names := make(map[string]bool, len(wans))
+	for _, spec := range wans {
+		wan, err := status.ParseWAN(spec)
+		if err != nil {
+			return nil, err
+		}
+		names[wan.Name] = true
+
+		wan.PingHosts = pingtargets
+		if targets, ok := pingGroups[wan.Name]; ok {
+			wan.PingHosts = targets
+		}
+		wan.TCPTargets = tcpTargets
+		if targets, ok := tcpGroups[wan.Name]; ok {
+			wan.TCPTargets = targets
+		}
+
+		ret = append(ret, wan)
+	}
+
+	for _, groups := range []map[string][]string{pingGroups, tcpGroups} {
+		for name := range groups {
+			if !names[name] {
+				return nil, fmt.Errorf("targets given for unknown WAN %q", name)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// newStatus builds a status checker from the global flags
+func newStatus() (*status.Status, error) {
+	s := status.NewStatus(pingCount, pingWorkerCount, pingtargets)
+
+	s.MTUTargets = mtuTargets
+
+	if dualStack {
+		s.Families = status.DualStack
+	}
+
+	wanList, err := newWANs()
+	if err != nil {
+		return nil, err
+	}
+	s.WANs = wanList
+
+	if len(pingtargets) > 0 || gatewayProbe || len(mtuTargets) > 0 || len(wanList) > 0 {
+		privileged, err := status.DetectPingMode(pingMode)
+		if err != nil {
+			var permErr *status.PingPermissionError
+			if !errors.As(err, &permErr) {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "Disabling ping checks: %v\n", err)
+			s.PingHosts = nil
+			s.MTUTargets = nil
+			for i := range s.WANs {
+				s.WANs[i].PingHosts = nil
+			}
+			gatewayProbe = false
+		}
+		s.Privileged = privileged
+	}
+
+	if gatewayProbe {
+		gateway, err := status.GatewayHost(hostname)
+		if err != nil {
+			return nil, err
+		}
+		s.Gateway = gateway
+	}
+
+	if len(dnsNames) > 0 {
+		gateway, err := status.GatewayResolver(hostname)
+		if err != nil {
+			return nil, err
+		}
+
+		s.DNSNames = dnsNames
+		s.DNSCount = dnsCount
+		s.DNSResolvers = append(s.DNSResolvers, gateway)
+		resolvers := dnsResolvers
+		// IPv6 lookups need resolvers reachable over IPv6
+		if dualStack && !rootCmd.PersistentFlags().Changed("resolvers") {
+			resolvers = append(resolvers, status.DefaultDNSResolvers6...)
+		}
+		for _, r := range resolvers {
+			s.DNSResolvers = append(s.DNSResolvers, status.NewDNSResolver(r, r))
+		}
+	}
+
+	s.TCPTargets = tcpTargets
+	s.TCPTimeout = time.Duration(tcpTimeout) * time.Second
+	s.HTTPTargets = httpTargets
+	s.HTTPTimeout = time.Duration(httpTimeout) * time.Second
+	s.HTTPExpectedCodes = httpExpect
+	s.EchoURL = echoURL
+	s.STUNServers = stunServers
+
+	return s, nil
+}
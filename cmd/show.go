@@ -5,14 +5,23 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/asciifaceman/gomo/pkg/clients"
 	"github.com/asciifaceman/gomo/pkg/clio"
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/status"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/spf13/cobra"
 )
 
-var pretty bool
+var (
+	pretty   bool
+	discover bool
+)
 
 // showCmd represents the show command
 var showCmd = &cobra.Command{
@@ -26,7 +35,20 @@ var showCmd = &cobra.Command{
 			return
 		}
 
+		s, err := newStatus()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
 		resp := c.Fetch()
+		report := s.Run()
+		if discover {
+			found := s.Discover()
+			report.MTU = found.MTU
+			report.Echo = found.Echo
+			report.NAT = found.NAT
+		}
 
 		if pretty {
 			p := clio.NewPrinter(40, 25, 2)
@@ -35,50 +57,356 @@ var showCmd = &cobra.Command{
 			if resp.Error != nil {
 				p.PrintHeader("Failed to fetch data")
 				p.PrintKVIndent("Error", resp.Error.Error())
-				return
+			} else {
+				printRadio(p, resp)
 			}
 
-			p.PrintKV("Online", resp.Body.ConnectionStatus[0].ConnectionStatus)
-			p.PrintKV("IPV6", resp.Body.ApCfg[0].IPV6)
-			p.PrintKV("Bytes Recv", fmt.Sprintf("%d (%.2fGB)", resp.StatCellular().BytesReceived, float64(resp.StatCellular().BytesReceived)*1e-9))
-			p.PrintKV("Bytes Sent", fmt.Sprintf("%d (%.2fGB)", resp.StatCellular().BytesSent, float64(resp.StatCellular().BytesSent)*1e-9))
-			p.PrintHeader("5G")
-			p.PrintKVIndent("Band", resp.Stat5G().Band)
-			p.PrintKVIndent("CellID", resp.Stat5G().PhysicalCellID)
-			fmt.Println("")
-			p.PrintKVIndent("SNR", resp.Stat5G().SNRCurrent)
-			p.PrintKVIndent("RSRP", resp.Stat5G().RSRPCurrent)
-			p.PrintKVIndent("RSRQ", resp.Stat5G().RSRQCurrent)
-			p.PrintHeader("LTE")
-			p.PrintKVIndent("Band", resp.StatLTE().Band)
-			p.PrintKVIndent("CellID", resp.StatLTE().PhysicalCellID)
-			fmt.Println("")
-			p.PrintKVIndent("SNR", resp.StatLTE().SNRCurrent)
-			p.PrintKVIndent("RSRP", resp.StatLTE().RSRPCurrent)
-			p.PrintKVIndent("RSRQ", resp.StatLTE().RSRQCurrent)
-			p.PrintKVIndent("RSSI", resp.StatLTE().RSSICurrent)
-			p.PrintHeader("Ethernet")
-			p.PrintKVIndent("Enabled", resp.StatEthernet().Enable)
-			p.PrintKVIndent("Status", resp.StatEthernet().Status)
-			fmt.Println("")
-			p.PrintKVIndent("Bytes Recv", fmt.Sprintf("%d (%.2fGB)", resp.StatEthernet().Stat.BytesReceived, float64(resp.StatEthernet().Stat.BytesReceived)*1e-9))
-			p.PrintKVIndent("Bytes Sent", fmt.Sprintf("%d (%.2fGB)", resp.StatEthernet().Stat.BytesSent, float64(resp.StatEthernet().Stat.BytesSent)*1e-9))
-			p.PrintHeader("TODO")
-			p.PrintKVIndent("Bring Back", "Ping Stats")
+			printStatus(p, report)
+
+			if len(report.Families) > 0 {
+				printFamilies(p, resp, report.Families)
+			}
+
+			if len(report.WANs) > 0 {
+				printWANs(p, report.WANs)
+			}
+
+			if report.Echo != nil {
+				printCGNAT(p, status.DetectCGNAT(wanAddress(resp), report.Echo))
+			}
+
+			if report.NAT != nil {
+				printNAT(p, report.NAT)
+			}
 
 		} else {
 			if resp.Error != nil {
 				fmt.Println(err)
 			}
 			spew.Dump(resp)
+			spew.Dump(report)
 		}
 
 	},
 }
 
+// printRadio prints the radio, cellular and ethernet stats of a fetch
+func printRadio(p *clio.Printer, resp *models.FastmileReturn) {
+	p.PrintKV("Online", resp.Body.ConnectionStatus[0].ConnectionStatus)
+	p.PrintKV("IPV6", resp.Body.ApCfg[0].IPV6)
+	p.PrintKV("Bytes Recv", fmt.Sprintf("%d (%.2fGB)", resp.StatCellular().BytesReceived, float64(resp.StatCellular().BytesReceived)*1e-9))
+	p.PrintKV("Bytes Sent", fmt.Sprintf("%d (%.2fGB)", resp.StatCellular().BytesSent, float64(resp.StatCellular().BytesSent)*1e-9))
+	p.PrintHeader("5G")
+	p.PrintKVIndent("Band", resp.Stat5G().Band)
+	p.PrintKVIndent("CellID", resp.Stat5G().PhysicalCellID)
+	fmt.Println("")
+	p.PrintKVIndent("SNR", resp.Stat5G().SNRCurrent)
+	p.PrintKVIndent("RSRP", resp.Stat5G().RSRPCurrent)
+	p.PrintKVIndent("RSRQ", resp.Stat5G().RSRQCurrent)
+	p.PrintHeader("LTE")
+	p.PrintKVIndent("Band", resp.StatLTE().Band)
+	p.PrintKVIndent("CellID", resp.StatLTE().PhysicalCellID)
+	fmt.Println("")
+	p.PrintKVIndent("SNR", resp.StatLTE().SNRCurrent)
+	p.PrintKVIndent("RSRP", resp.StatLTE().RSRPCurrent)
+	p.PrintKVIndent("RSRQ", resp.StatLTE().RSRQCurrent)
+	p.PrintKVIndent("RSSI", resp.StatLTE().RSSICurrent)
+	p.PrintHeader("Ethernet")
+	p.PrintKVIndent("Enabled", resp.StatEthernet().Enable)
+	p.PrintKVIndent("Status", resp.StatEthernet().Status)
+	fmt.Println("")
+	p.PrintKVIndent("Bytes Recv", fmt.Sprintf("%d (%.2fGB)", resp.StatEthernet().Stat.BytesReceived, float64(resp.StatEthernet().Stat.BytesReceived)*1e-9))
+	p.PrintKVIndent("Bytes Sent", fmt.Sprintf("%d (%.2fGB)", resp.StatEthernet().Stat.BytesSent, float64(resp.StatEthernet().Stat.BytesSent)*1e-9))
+}
+
+// wanAddress returns the trashcan's WAN IPv4 address from a fetch
+func wanAddress(resp *models.FastmileReturn) string {
+	if resp.Error != nil || resp.Body == nil || len(resp.Body.ApCfg) == 0 {
+		return ""
+	}
+	return resp.Body.ApCfg[0].IPV4
+}
+
+// printFamilies prints the per address family comparison of a dual stack
+// check alongside the trashcan's WAN address in each family
+func printFamilies(p *clio.Printer, resp *models.FastmileReturn, reports []*models.FamilyReport) {
+	p.PrintHeader("Dual Stack")
+	if resp.Error == nil && resp.Body != nil && len(resp.Body.ApCfg) > 0 {
+		p.PrintKVIndent("WAN IPv4", resp.Body.ApCfg[0].IPV4)
+		p.PrintKVIndent("WAN IPv6", resp.Body.ApCfg[0].IPV6)
+		fmt.Println("")
+	}
+	for i, f := range reports {
+		if i > 0 {
+			fmt.Println("")
+		}
+		p.PrintKVIndent("Family", status.FamilyName(f.Family))
+		p.PrintKVIndent("Reachable", fmt.Sprintf("%v (%d/%d probes)", f.Reachable, f.Succeeded, f.Probes))
+		if f.Pings > 0 {
+			p.PrintKVIndent("Ping", fmt.Sprintf("%.1f%% loss / %s avg", f.Loss*100, f.Latency.Round(time.Microsecond)))
+		}
+		if f.Connect > 0 {
+			p.PrintKVIndent("TCP Connect", f.Connect.Round(time.Microsecond))
+		}
+		p.PrintKVIndent("DNS Failures", fmt.Sprintf("%.0f%%", f.DNSFailureRate*100))
+		if f.Broken {
+			p.PrintKVIndent("BROKEN", f.Reason)
+		} else if f.Reason != "" {
+			p.PrintKVIndent("Note", f.Reason)
+		}
+	}
+}
+
+// printWANs prints the WANs side by side along with how each compares to the
+// first WAN
+func printWANs(p *clio.Printer, reports []*models.WANReport) {
+	p.PrintHeader("WANs")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WAN\tSOURCE\tUP\tLOSS\tLATENCY\tJITTER\tCONNECT\tVS "+strings.ToUpper(reports[0].Name))
+	for i, r := range reports {
+		source := r.Source
+		if r.Address != "" && r.Address != r.Source {
+			source = fmt.Sprintf("%s (%s)", r.Source, r.Address)
+		}
+		delta := "-"
+		if i > 0 && r.Up && reports[0].Up {
+			latency := (r.Latency - reports[0].Latency).Round(time.Microsecond)
+			sign := "+"
+			if latency < 0 {
+				sign = ""
+			}
+			delta = fmt.Sprintf("%+.1f%% loss / %s%s", (r.Loss-reports[0].Loss)*100, sign, latency)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%.1f%%\t%s\t%s\t%s\t%s\n",
+			r.Name,
+			source,
+			r.Up,
+			r.Loss*100,
+			r.Latency.Round(time.Microsecond),
+			r.Jitter.Round(time.Microsecond),
+			r.Connect.Round(time.Microsecond),
+			delta,
+		)
+	}
+	w.Flush()
+
+	for _, r := range reports {
+		if r.Error != "" {
+			p.PrintKVIndent(r.Name, r.Error)
+			continue
+		}
+		for _, ping := range r.Pings {
+			if ping.Error != nil && !ping.NoRecord {
+				p.PrintKVIndent(r.Name, ping.Error.Error())
+			}
+		}
+		for _, tcp := range r.TCP {
+			if tcp.Error != "" && !tcp.NoRecord {
+				p.PrintKVIndent(r.Name, tcp.Error)
+			}
+		}
+	}
+}
+
+// printCGNAT prints the result of CGNAT detection
+func printCGNAT(p *clio.Printer, report *models.CGNATReport) {
+	p.PrintHeader("CGNAT")
+	p.PrintKVIndent("WAN", report.WANAddress)
+	if report.PublicAddress != "" {
+		p.PrintKVIndent("Public", report.PublicAddress)
+	}
+	p.PrintKVIndent("Shared Space", report.SharedSpace)
+	p.PrintKVIndent("Translated", report.Translated)
+	p.PrintKVIndent("Behind CGNAT", report.CGNAT)
+	if report.Error != "" {
+		p.PrintKVIndent("Error", report.Error)
+	}
+}
+
+// printNAT prints the result of STUN NAT type detection
+func printNAT(p *clio.Printer, report *models.NATReport) {
+	p.PrintHeader("NAT")
+	for _, s := range report.Servers {
+		if s.Error != "" {
+			p.PrintKVIndent(s.Server, s.Error)
+			continue
+		}
+		p.PrintKVIndent(s.Server, fmt.Sprintf("%s in %s", s.Mapped, s.RTT.Round(time.Microsecond)))
+	}
+	fmt.Println("")
+	p.PrintKVIndent("Local", report.LocalAddress)
+	if report.MappedAddress != "" {
+		p.PrintKVIndent("Mapped", report.MappedAddress)
+	}
+	p.PrintKVIndent("Mapping", report.Mapping)
+	p.PrintKVIndent("Filtering", report.Filtering)
+	p.PrintKVIndent("Port Preserved", report.PortPreserved)
+	p.PrintKVIndent("Type", fmt.Sprintf("%s (%s)", report.Type, report.Console))
+	if report.Error != "" {
+		p.PrintKVIndent("Error", report.Error)
+	}
+}
+
+// verdicts describes each LAN vs WAN verdict
+var verdicts = map[string]string{
+	status.VerdictHealthy: "healthy",
+	status.VerdictLAN:     "local network (WiFi/Ethernet) trouble",
+	status.VerdictWAN:     "cellular or upstream trouble",
+	status.VerdictBoth:    "local network and cellular trouble",
+	status.VerdictUnknown: "unknown",
+}
+
+// probeTarget labels a target with the address family it was probed over
+func probeTarget(target string, family string) string {
+	if family == "" {
+		return target
+	}
+	return fmt.Sprintf("%s (%s)", target, status.FamilyName(family))
+}
+
+// printPing prints the results of a ping check against a single target
+func printPing(p *clio.Printer, ping *models.PingReportReturn) {
+	p.PrintKVIndent("Target", probeTarget(ping.Hostname, ping.Family))
+	if ping.NoRecord {
+		p.PrintKVIndent("Skipped", ping.Error.Error())
+		return
+	}
+	if ping.Error != nil {
+		p.PrintKVIndent("Error", ping.Error.Error())
+		return
+	}
+	p.PrintKVIndent("Loss", fmt.Sprintf("%.1f%% (%d/%d)", ping.Body.PacketLoss, ping.Body.PacketsRecv, ping.Body.PacketsSent))
+	p.PrintKVIndent("RTT min/avg/max", fmt.Sprintf("%s/%s/%s",
+		ping.Body.MinResponseTime.Round(time.Microsecond),
+		ping.Body.AvgResponseTime.Round(time.Microsecond),
+		ping.Body.MaxResponseTime.Round(time.Microsecond),
+	))
+	p.PrintKVIndent("Jitter", ping.Body.Jitter.Round(time.Microsecond))
+	if ping.Body.LossStreakMax > 0 {
+		p.PrintKVIndent("Longest Loss", fmt.Sprintf("%d packets", ping.Body.LossStreakMax))
+	}
+}
+
+// printStatus prints the results of the network status checks
+func printStatus(p *clio.Printer, report *models.StatusReport) {
+	if len(report.Pings) > 0 {
+		p.PrintHeader("Ping")
+	}
+	for i, ping := range report.Pings {
+		if i > 0 {
+			fmt.Println("")
+		}
+		printPing(p, ping)
+	}
+
+	if report.Gateway != nil {
+		p.PrintHeader("Gateway")
+		printPing(p, report.Gateway)
+	}
+
+	if report.Split != nil {
+		p.PrintHeader("LAN vs WAN")
+		if report.Split.Verdict != status.VerdictUnknown {
+			p.PrintKVIndent("LAN", fmt.Sprintf("%.1f%% loss / %s avg", report.Split.LAN.Loss*100, report.Split.LAN.Latency.Round(time.Microsecond)))
+			p.PrintKVIndent("WAN", fmt.Sprintf("%.1f%% loss / %s added", report.Split.WAN.Loss*100, report.Split.WAN.Latency.Round(time.Microsecond)))
+		}
+		p.PrintKVIndent("Verdict", verdicts[report.Split.Verdict])
+		if report.Split.Reason != "" {
+			p.PrintKVIndent("Reason", report.Split.Reason)
+		}
+	}
+
+	if len(report.MTU) > 0 {
+		p.PrintHeader("Path MTU")
+	}
+	for i, mtu := range report.MTU {
+		if i > 0 {
+			fmt.Println("")
+		}
+		p.PrintKVIndent("Target", mtu.Target)
+		if mtu.MTU > 0 {
+			p.PrintKVIndent("MTU", fmt.Sprintf("%d (%d probes)", mtu.MTU, mtu.Probes))
+		}
+		if mtu.Error != "" {
+			p.PrintKVIndent("Error", mtu.Error)
+		}
+	}
+
+	if len(report.DNS) > 0 {
+		p.PrintHeader("DNS")
+	}
+	for i, dns := range report.DNS {
+		if i > 0 {
+			fmt.Println("")
+		}
+		p.PrintKVIndent("Resolver", fmt.Sprintf("%s (%s)", dns.Resolver, dns.Address))
+		p.PrintKVIndent("Name", probeTarget(dns.Name, dns.Family))
+		if dns.NoRecord {
+			p.PrintKVIndent("Skipped", dns.Error)
+			continue
+		}
+		p.PrintKVIndent("Failures", fmt.Sprintf("%.0f%% (%d/%d)", dns.FailureRate*100, dns.Failures, dns.Lookups))
+		p.PrintKVIndent("Lookup min/avg/max", fmt.Sprintf("%s/%s/%s",
+			dns.MinLatency.Round(time.Microsecond),
+			dns.AvgLatency.Round(time.Microsecond),
+			dns.MaxLatency.Round(time.Microsecond),
+		))
+		if dns.Mismatch {
+			p.PrintKVIndent("Mismatch", strings.Join(dns.Answers, ","))
+		}
+		if dns.Error != "" {
+			p.PrintKVIndent("Error", dns.Error)
+		}
+	}
+
+	if len(report.TCP) > 0 {
+		p.PrintHeader("TCP")
+	}
+	for i, tcp := range report.TCP {
+		if i > 0 {
+			fmt.Println("")
+		}
+		p.PrintKVIndent("Target", probeTarget(tcp.Target, tcp.Family))
+		if tcp.NoRecord {
+			p.PrintKVIndent("Skipped", tcp.Error)
+			continue
+		}
+		if tcp.Error != "" {
+			p.PrintKVIndent("Error", tcp.Error)
+			continue
+		}
+		p.PrintKVIndent("DNS", tcp.Phases.DNS.Round(time.Microsecond))
+		p.PrintKVIndent("Connect", tcp.Phases.Connect.Round(time.Microsecond))
+	}
+
+	if len(report.HTTP) > 0 {
+		p.PrintHeader("HTTP")
+	}
+	for i, h := range report.HTTP {
+		if i > 0 {
+			fmt.Println("")
+		}
+		p.PrintKVIndent("URL", h.URL)
+		if h.StatusCode != 0 {
+			p.PrintKVIndent("Status", fmt.Sprintf("%d (expected: %v)", h.StatusCode, h.Expected))
+		}
+		if h.Error != "" {
+			p.PrintKVIndent("Error", h.Error)
+			continue
+		}
+		p.PrintKVIndent("DNS", h.Phases.DNS.Round(time.Microsecond))
+		p.PrintKVIndent("Connect", h.Phases.Connect.Round(time.Microsecond))
+		p.PrintKVIndent("TLS", h.Phases.TLS.Round(time.Microsecond))
+		p.PrintKVIndent("TTFB", h.Phases.TTFB.Round(time.Microsecond))
+		p.PrintKVIndent("Total", h.Phases.Total.Round(time.Microsecond))
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(showCmd)
 	showCmd.PersistentFlags().BoolVar(&pretty, "pretty", false, "Print a prettified table layout instead of raw data")
+	showCmd.PersistentFlags().BoolVar(&discover, "discover", false, "Also discover the path MTU, check for CGNAT and test the NAT type, which takes a few seconds")
 
 	// Here you will define your flags and configuration settings.
 
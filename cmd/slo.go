@@ -0,0 +1,111 @@
+/*
+Copyright © 2023 Charles Corbett <github.com/asciifaceman>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/slo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	sloFile = "slo.json"
+)
+
+// sloCmd represents the slo command
+var sloCmd = &cobra.Command{
+	Use:   "slo",
+	Short: "Show SLO compliance and remaining error budget",
+	Long: `Show the compliance and remaining error budget of every SLO
+defined under slos in the config file, as recorded by the daemon's
+status checks. A latency SLO counts every ping answered under its
+threshold as good, a loss SLO every ping answered and an availability
+SLO every check where the internet was reachable. Burn rate is how
+fast the budget was spent over the last hour, above 1 the budget runs
+out before the window ends.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		objectives, err := loadSLOs()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if len(objectives) == 0 {
+			fmt.Println("No SLOs configured, define them under slos in the config file")
+			return
+		}
+
+		t, err := slo.Load(dataPath(sloFile), objectives)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SLO\tOBJECTIVE\tCOVERED\tEVENTS\tCOMPLIANCE\tBUDGET LEFT\tBURN RATE\tSTATUS")
+		for _, s := range t.Report(time.Now()) {
+			state := "met"
+			if s.Total == 0 {
+				state = "no data"
+			} else if !s.Met {
+				state = "VIOLATED"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.3f%%\t%.1f%%\t%.2f\t%s\n",
+				s.Objective.Name,
+				s.Objective,
+				s.Covered.Round(time.Minute),
+				s.Total,
+				s.Compliance*100,
+				s.BudgetRemaining*100,
+				s.BurnRate,
+				state,
+			)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sloCmd)
+}
+
+// loadSLOs parses the SLOs defined in the config file
+func loadSLOs() ([]*slo.Objective, error) {
+	var configs []slo.Config
+	if err := viper.UnmarshalKey("slos", &configs); err != nil {
+		return nil, fmt.Errorf("invalid slos config: %w", err)
+	}
+
+	objectives := make([]*slo.Objective, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		o, err := slo.Parse(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if names[o.Name] {
+			return nil, fmt.Errorf("SLO %s is defined more than once", o.Name)
+		}
+		names[o.Name] = true
+		objectives = append(objectives, o)
+	}
+
+	return objectives, nil
+}
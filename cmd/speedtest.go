@@ -0,0 +1,130 @@
+/*
+Copyright © 2023 Charles Corbett <github.com/asciifaceman>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/clio"
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/speedtest"
+	"github.com/asciifaceman/gomo/pkg/tmo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	speedTestDownloadURL = speedtest.DefaultDownloadURL
+	speedTestUploadURL   = speedtest.DefaultUploadURL
+	speedTestStreams     = speedtest.DefaultStreams
+	speedTestWarmUp      = int(speedtest.DefaultWarmUp.Seconds())
+	speedTestDuration    = int(speedtest.DefaultDuration.Seconds())
+)
+
+// speedtestCmd represents the speedtest command
+var speedtestCmd = &cobra.Command{
+	Use:   "speedtest",
+	Short: "Measure download and upload throughput",
+	Long: `Measure download and upload throughput against HTTP endpoints
+using parallel streams, along with latency under load. Results are
+tagged with the radio band, PCI and SNR at the start of the test.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := newSpeedTest()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		fmt.Printf("Testing with %d streams for %ds after a %ds warm up...\n", speedTestStreams, speedTestDuration, speedTestWarmUp)
+		report := s.Run(ctx)
+
+		p := clio.NewPrinter(40, 25, 2)
+		p.PrintHeader(fmt.Sprintf("Gomo %s", version))
+		printSpeedTest(p, report)
+	},
+}
+
+// addSpeedTestFlags adds the speed test flags to a command's flag set
+func addSpeedTestFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&speedTestDownloadURL, "download-url", speedTestDownloadURL, "URL to download from during speed tests, empty to skip")
+	flags.StringVar(&speedTestUploadURL, "upload-url", speedTestUploadURL, "URL to POST to during speed tests, empty to skip")
+	flags.IntVar(&speedTestStreams, "streams", speedTestStreams, "number of parallel streams per speed test direction")
+	flags.IntVar(&speedTestWarmUp, "warmup", speedTestWarmUp, "seconds of each speed test direction to discard while TCP ramps up")
+	flags.IntVar(&speedTestDuration, "duration", speedTestDuration, "seconds to measure each speed test direction for")
+}
+
+// newSpeedTest builds a speed test from the speed test flags
+func newSpeedTest() (*speedtest.SpeedTest, error) {
+	t, err := tmo.NewTrashcan(hostname, time.Duration(reqtimeout)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := speedtest.DefaultConfig()
+	cfg.DownloadURL = speedTestDownloadURL
+	cfg.UploadURL = speedTestUploadURL
+	cfg.Streams = speedTestStreams
+	cfg.WarmUp = time.Duration(speedTestWarmUp) * time.Second
+	cfg.Duration = time.Duration(speedTestDuration) * time.Second
+
+	return speedtest.New(cfg, t)
+}
+
+// printSpeedTest prints the results of a speed test
+func printSpeedTest(p *clio.Printer, report *models.SpeedTestReport) {
+	p.PrintHeader("Radio")
+	if report.RadioError != "" {
+		p.PrintKVIndent("Error", report.RadioError)
+	} else {
+		p.PrintKVIndent("5G", fmt.Sprintf("%s/%s SNR %.0f", report.Radio5G.Band, report.Radio5G.PCI, report.Radio5G.SNR))
+		p.PrintKVIndent("LTE", fmt.Sprintf("%s/%s SNR %.0f", report.RadioLTE.Band, report.RadioLTE.PCI, report.RadioLTE.SNR))
+	}
+	p.PrintKVIndent("Idle Latency", fmt.Sprintf("%s avg / %s max",
+		report.IdleLatency.Avg.Round(time.Microsecond),
+		report.IdleLatency.Max.Round(time.Microsecond),
+	))
+
+	for _, t := range []*models.ThroughputReport{report.Download, report.Upload} {
+		if t == nil {
+			continue
+		}
+		p.PrintHeader(t.Direction)
+		p.PrintKVIndent("Throughput", fmt.Sprintf("%.2f Mbps", t.BitsPerSecond/1e6))
+		p.PrintKVIndent("Transferred", fmt.Sprintf("%.2fMB in %s", float64(t.Bytes)/1e6, t.Duration.Round(time.Millisecond)))
+		p.PrintKVIndent("Stream StdDev", fmt.Sprintf("%.2f Mbps", t.StreamStdDev/1e6))
+		p.PrintKVIndent("Loaded Latency", fmt.Sprintf("%s avg / %s max",
+			t.Latency.Avg.Round(time.Microsecond),
+			t.Latency.Max.Round(time.Microsecond),
+		))
+		if t.Error != "" {
+			p.PrintKVIndent("Error", t.Error)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(speedtestCmd)
+
+	addSpeedTestFlags(speedtestCmd.Flags())
+}
@@ -0,0 +1,275 @@
+// package cells keeps a persistent history of every cell (PCI/band combination)
+// the trashcan has been attached to along with signal quality seen on each
+package cells
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/radiofreq"
+	"github.com/asciifaceman/gomo/pkg/store"
+)
+
+const (
+	// DefaultMaxGap is the longest time between two observations of the same
+	// cell that still counts towards its attached time
+	DefaultMaxGap = 5 * time.Minute
+)
+
+// Cell is a single PCI/band combination the trashcan has attached to
+type Cell struct {
+	Spectrum  string        `json:"spectrum"`
+	Band      string        `json:"band"`
+	PCI       string        `json:"pci"`
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+	Attached  time.Duration `json:"attached"`
+	Samples   int           `json:"samples"`
+	RSRP      *Distribution `json:"rsrp"`
+	SNR       *Distribution `json:"snr"`
+}
+
+// Key returns the unique key for a cell
+func (c *Cell) Key() string {
+	return Key(c.Spectrum, c.Band, c.PCI)
+}
+
+// Key builds the unique key for a spectrum, band and PCI
+func Key(spectrum string, band string, pci string) string {
+	return fmt.Sprintf("%s/%s/%s", spectrum, band, pci)
+}
+
+// Distribution tracks how often each whole dB/dBm value was observed so
+// best, worst and median can be derived without keeping every sample
+type Distribution struct {
+	Counts map[int]int `json:"counts"`
+}
+
+// NewDistribution returns an empty Distribution
+func NewDistribution() *Distribution {
+	return &Distribution{
+		Counts: make(map[int]int),
+	}
+}
+
+// Add records a single observation
+func (d *Distribution) Add(val float64) {
+	if d.Counts == nil {
+		d.Counts = make(map[int]int)
+	}
+	if val < 0 {
+		d.Counts[int(val-0.5)]++
+		return
+	}
+	d.Counts[int(val+0.5)]++
+}
+
+// Total returns the number of observations recorded
+func (d *Distribution) Total() int {
+	total := 0
+	for _, c := range d.Counts {
+		total += c
+	}
+	return total
+}
+
+func (d *Distribution) values() []int {
+	vals := make([]int, 0, len(d.Counts))
+	for v := range d.Counts {
+		vals = append(vals, v)
+	}
+	sort.Ints(vals)
+	return vals
+}
+
+// Best returns the highest observed value, else 0
+func (d *Distribution) Best() float64 {
+	vals := d.values()
+	if len(vals) == 0 {
+		return 0
+	}
+	return float64(vals[len(vals)-1])
+}
+
+// Worst returns the lowest observed value, else 0
+func (d *Distribution) Worst() float64 {
+	vals := d.values()
+	if len(vals) == 0 {
+		return 0
+	}
+	return float64(vals[0])
+}
+
+// Median returns the median observed value, else 0
+func (d *Distribution) Median() float64 {
+	total := d.Total()
+	if total == 0 {
+		return 0
+	}
+
+	vals := d.values()
+	lower, upper := (total-1)/2, total/2
+	var lowVal, highVal int
+	seen := 0
+	for _, v := range vals {
+		next := seen + d.Counts[v]
+		if lower >= seen && lower < next {
+			lowVal = v
+		}
+		if upper >= seen && upper < next {
+			highVal = v
+			break
+		}
+		seen = next
+	}
+
+	return float64(lowVal+highVal) / 2
+}
+
+// History is the on-disk record of known cells
+type History struct {
+	mu     sync.Mutex
+	path   string
+	MaxGap time.Duration `json:"-"`
+
+	Cells   map[string]*Cell  `json:"cells"`
+	Current map[string]string `json:"current"`
+}
+
+// NewHistory returns an empty History which will be saved to path
+func NewHistory(path string) *History {
+	return &History{
+		path:    path,
+		MaxGap:  DefaultMaxGap,
+		Cells:   make(map[string]*Cell),
+		Current: make(map[string]string),
+	}
+}
+
+// Load reads a History from path. A missing file returns an empty History
+func Load(path string) (*History, error) {
+	h := NewHistory(path)
+
+	if _, err := store.LoadJSON(path, h); err != nil {
+		return nil, err
+	}
+
+	if h.Cells == nil {
+		h.Cells = make(map[string]*Cell)
+	}
+	if h.Current == nil {
+		h.Current = make(map[string]string)
+	}
+
+	return h, nil
+}
+
+// Save writes the History to its path
+func (h *History) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return store.SaveJSON(h.path, h)
+}
+
+// Observe records the cells in a fastmile payload as seen at time at. Returns
+// true when a radio attached, detached or handed over to another cell
+func (h *History) Observe(status *models.FastmileRadioStatus, at time.Time) bool {
+	if status == nil {
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nr, lte := h.Current[radiofreq.S_5G.String()], h.Current[radiofreq.S_LTE.String()]
+
+	if len(status.Cell5GStats) > 0 && status.Cell5GStats[0] != nil && status.Cell5GStats[0].Stat != nil {
+		s := status.Cell5GStats[0].Stat
+		h.observe(radiofreq.S_5G.String(), s.Band, s.PhysicalCellID, s.RSRPCurrent, s.SNRCurrent, at)
+	}
+
+	if len(status.CellLTEStats) > 0 && status.CellLTEStats[0] != nil && status.CellLTEStats[0].Stat != nil {
+		s := status.CellLTEStats[0].Stat
+		h.observe(radiofreq.S_LTE.String(), s.Band, s.PhysicalCellID, s.RSRPCurrent, s.SNRCurrent, at)
+	}
+
+	return h.Current[radiofreq.S_5G.String()] != nr || h.Current[radiofreq.S_LTE.String()] != lte
+}
+
+func (h *History) observe(spectrum string, band string, pci string, rsrp float64, snr float64, at time.Time) {
+	if band == "" || pci == "" {
+		delete(h.Current, spectrum)
+		return
+	}
+
+	key := Key(spectrum, band, pci)
+	c, ok := h.Cells[key]
+	if !ok {
+		c = &Cell{
+			Spectrum:  spectrum,
+			Band:      band,
+			PCI:       pci,
+			FirstSeen: at,
+			RSRP:      NewDistribution(),
+			SNR:       NewDistribution(),
+		}
+		h.Cells[key] = c
+	}
+
+	if h.Current[spectrum] == key && !c.LastSeen.IsZero() {
+		if gap := at.Sub(c.LastSeen); gap > 0 && gap <= h.MaxGap {
+			c.Attached += gap
+		}
+	}
+
+	c.LastSeen = at
+	c.Samples++
+	c.RSRP.Add(rsrp)
+	c.SNR.Add(snr)
+
+	h.Current[spectrum] = key
+}
+
+// List returns every known cell
+func (h *History) List() []*Cell {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ret := make([]*Cell, 0, len(h.Cells))
+	for _, c := range h.Cells {
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// Sorters maps sort keys to less functions which order cells best/most recent first
+var Sorters = map[string]func(a *Cell, b *Cell) bool{
+	"last_seen":  func(a *Cell, b *Cell) bool { return a.LastSeen.After(b.LastSeen) },
+	"first_seen": func(a *Cell, b *Cell) bool { return a.FirstSeen.After(b.FirstSeen) },
+	"attached":   func(a *Cell, b *Cell) bool { return a.Attached > b.Attached },
+	"samples":    func(a *Cell, b *Cell) bool { return a.Samples > b.Samples },
+	"rsrp":       func(a *Cell, b *Cell) bool { return a.RSRP.Median() > b.RSRP.Median() },
+	"snr":        func(a *Cell, b *Cell) bool { return a.SNR.Median() > b.SNR.Median() },
+	"band":       func(a *Cell, b *Cell) bool { return a.Key() < b.Key() },
+}
+
+// Sort orders cells in place by the given sort key
+func Sort(cells []*Cell, by string, reverse bool) error {
+	less, ok := Sorters[by]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q", by)
+	}
+
+	sort.SliceStable(cells, func(i, j int) bool {
+		if reverse {
+			return less(cells[j], cells[i])
+		}
+		return less(cells[i], cells[j])
+	})
+
+	return nil
+}
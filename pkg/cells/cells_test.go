@@ -0,0 +1,84 @@
+package cells
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func fakeStatus(band string, pci string, rsrp float64, snr float64) *models.FastmileRadioStatus {
+	return &models.FastmileRadioStatus{
+		Cell5GStats: []*models.Cell5GStats{
+			{Stat: &models.Cell5GStat{Band: band, PhysicalCellID: pci, RSRPCurrent: rsrp, SNRCurrent: snr}},
+		},
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	d := NewDistribution()
+	for _, v := range []float64{-100, -105, -98, -110} {
+		d.Add(v)
+	}
+
+	if d.Best() != -98 {
+		t.Fatalf("Expected best of -98 but got %f", d.Best())
+	}
+	if d.Worst() != -110 {
+		t.Fatalf("Expected worst of -110 but got %f", d.Worst())
+	}
+	if d.Median() != -102.5 {
+		t.Fatalf("Expected median of -102.5 but got %f", d.Median())
+	}
+}
+
+func TestObserveAttached(t *testing.T) {
+	h := NewHistory(filepath.Join(t.TempDir(), "cells.json"))
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if !h.Observe(fakeStatus("n41", "392", -100, 10), start) {
+		t.Fatalf("Expected attaching to report a change")
+	}
+	if h.Observe(fakeStatus("n41", "392", -102, 8), start.Add(15*time.Second)) {
+		t.Fatalf("Expected staying on a cell not to report a change")
+	}
+	if !h.Observe(fakeStatus("n71", "41", -90, 12), start.Add(30*time.Second)) {
+		t.Fatalf("Expected a handover to report a change")
+	}
+	h.Observe(fakeStatus("n41", "392", -101, 9), start.Add(45*time.Second))
+	// daemon was down, gap is not attached time
+	h.Observe(fakeStatus("n41", "392", -101, 9), start.Add(time.Hour))
+
+	c := h.Cells[Key("5G", "n41", "392")]
+	if c == nil {
+		t.Fatalf("Expected cell 5G/n41/392 to be recorded")
+	}
+	if c.Attached != 15*time.Second {
+		t.Fatalf("Expected 15s attached but got %s", c.Attached)
+	}
+	if c.Samples != 4 {
+		t.Fatalf("Expected 4 samples but got %d", c.Samples)
+	}
+	if !c.FirstSeen.Equal(start) {
+		t.Fatalf("Expected first seen %s but got %s", start, c.FirstSeen)
+	}
+
+	// a radio reported as null is skipped rather than dereferenced
+	h.Observe(&models.FastmileRadioStatus{Cell5GStats: []*models.Cell5GStats{nil}, CellLTEStats: []*models.CellLTEStats{nil}}, start.Add(time.Hour+15*time.Second))
+
+	if err := h.Save(); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+
+	loaded, err := Load(h.path)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(loaded.Cells) != 2 {
+		t.Fatalf("Expected 2 cells after reload but got %d", len(loaded.Cells))
+	}
+	if loaded.Cells[c.Key()].RSRP.Median() != c.RSRP.Median() {
+		t.Fatalf("Expected median RSRP to survive reload")
+	}
+}
@@ -0,0 +1,139 @@
+package clients
+
+import (
+	"math"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+const (
+	DefaultPollInterval = 15 * time.Second
+	DefaultPollMin      = 5 * time.Second
+	DefaultPollMax      = 60 * time.Second
+
+	// DefaultSignalDelta is the change in SNR or RSRP between scrapes, in dB,
+	// which counts as the signal changing fast
+	DefaultSignalDelta = 3
+)
+
+// AdaptivePoll picks the next poll interval from each scrape of the trashcan.
+// It polls at Min during outages, handovers and fast signal changes, backs off
+// towards Max while the trashcan is slow to answer and otherwise settles back
+// on Base
+type AdaptivePoll struct {
+	Base        time.Duration
+	Min         time.Duration
+	Max         time.Duration
+	SignalDelta float64
+
+	current time.Duration
+	last    *radioSample
+}
+
+// radioSample is the part of a scrape compared between polls
+type radioSample struct {
+	cells [2]string
+	snr   [2]float64
+	rsrp  [2]float64
+}
+
+// NewAdaptivePoll returns an AdaptivePoll starting at base
+func NewAdaptivePoll(base time.Duration, min time.Duration, max time.Duration) *AdaptivePoll {
+	if min <= 0 || min > base {
+		min = base
+	}
+	if max < base {
+		max = base
+	}
+	return &AdaptivePoll{
+		Base:        base,
+		Min:         min,
+		Max:         max,
+		SignalDelta: DefaultSignalDelta,
+		current:     base,
+	}
+}
+
+// Current returns the interval last picked
+func (a *AdaptivePoll) Current() time.Duration {
+	return a.current
+}
+
+// Next returns the interval to wait before the next poll after ret, and why
+// it changed or an empty reason if nothing in particular is going on. outage
+// is true if the connection is considered down
+func (a *AdaptivePoll) Next(ret *models.FastmileReturn, outage bool) (time.Duration, string) {
+	sample := sampleRadio(ret)
+	last := a.last
+	if sample != nil {
+		a.last = sample
+	}
+
+	// a slow trashcan is only made slower by asking it more often, even mid outage
+	if ret.Duration > a.current/2 {
+		a.current = minDuration(a.current*2, a.Max)
+		return a.current, "trashcan slow to respond"
+	}
+
+	switch {
+	case outage || ret.Error != nil:
+		a.current = a.Min
+		return a.current, "outage"
+	case sample != nil && last != nil && sample.cells != last.cells:
+		a.current = a.Min
+		return a.current, "handover"
+	case sample != nil && last != nil && sample.changedFrom(last, a.SignalDelta):
+		a.current = a.Min
+		return a.current, "signal changing"
+	}
+
+	// settle back on Base a step at a time so a flapping link isn't missed
+	if a.current < a.Base {
+		a.current = minDuration(a.current*2, a.Base)
+	} else if a.current > a.Base {
+		a.current = maxDuration(a.current/2, a.Base)
+	}
+	return a.current, ""
+}
+
+func sampleRadio(ret *models.FastmileReturn) *radioSample {
+	if ret.Error != nil || ret.Body == nil || len(ret.Body.Cell5GStats) == 0 || len(ret.Body.CellLTEStats) == 0 {
+		return nil
+	}
+	if ret.Body.Cell5GStats[0] == nil || ret.Body.CellLTEStats[0] == nil {
+		return nil
+	}
+	nr, lte := ret.Stat5G(), ret.StatLTE()
+	if nr == nil || lte == nil {
+		return nil
+	}
+	return &radioSample{
+		cells: [2]string{nr.Band + "/" + nr.PhysicalCellID, lte.Band + "/" + lte.PhysicalCellID},
+		snr:   [2]float64{nr.SNRCurrent, lte.SNRCurrent},
+		rsrp:  [2]float64{nr.RSRPCurrent, lte.RSRPCurrent},
+	}
+}
+
+func (s *radioSample) changedFrom(last *radioSample, delta float64) bool {
+	for i := range s.snr {
+		if math.Abs(s.snr[i]-last.snr[i]) >= delta || math.Abs(s.rsrp[i]-last.rsrp[i]) >= delta {
+			return true
+		}
+	}
+	return false
+}
+
+func minDuration(a time.Duration, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxDuration(a time.Duration, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,41 @@
+package clients
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func TestAdaptivePoll(t *testing.T) {
+	a := NewAdaptivePoll(15*time.Second, 5*time.Second, 60*time.Second)
+	fast := 100 * time.Millisecond
+
+	steps := []struct {
+		name     string
+		ret      *models.FastmileReturn
+		outage   bool
+		expected time.Duration
+	}{
+		{"steady", radioScrape("n41", "392", 10, fast), false, 15 * time.Second},
+		{"handover", radioScrape("n41", "120", 10, fast), false, 5 * time.Second},
+		{"settling", radioScrape("n41", "120", 11, fast), false, 10 * time.Second},
+		{"signal drop", radioScrape("n41", "120", 4, fast), false, 5 * time.Second},
+		{"outage", &models.FastmileReturn{Error: errors.New("refused"), Duration: fast}, false, 5 * time.Second},
+		{"wan down", radioScrape("n41", "120", 4, fast), true, 5 * time.Second},
+		{"slow", radioScrape("n41", "120", 4, 4*time.Second), true, 10 * time.Second},
+		{"slower", radioScrape("n41", "120", 4, 8*time.Second), false, 20 * time.Second},
+		{"timeout", &models.FastmileReturn{Error: errors.New("timeout"), Duration: 15 * time.Second}, false, 40 * time.Second},
+		{"still slow", radioScrape("n41", "120", 4, 30*time.Second), false, 60 * time.Second},
+		{"recovered", radioScrape("n41", "120", 4, fast), false, 30 * time.Second},
+		{"recovered again", radioScrape("n41", "120", 4, fast), false, 15 * time.Second},
+		{"null radio", &models.FastmileReturn{Body: &models.FastmileRadioStatus{Cell5GStats: []*models.Cell5GStats{nil}, CellLTEStats: []*models.CellLTEStats{nil}}, Duration: fast}, false, 15 * time.Second},
+	}
+
+	for _, step := range steps {
+		if got, reason := a.Next(step.ret, step.outage); got != step.expected {
+			t.Fatalf("%s: expected %s but got %s (%s)", step.name, step.expected, got, reason)
+		}
+	}
+}
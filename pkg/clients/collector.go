@@ -0,0 +1,90 @@
+package clients
+
+import (
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Collector fetches from the trashcan when prometheus scrapes rather than on
+// the daemon's poll loop, so the scrape interval controls sampling. A fetch is
+// reused for TTL and concurrent scrapes share a single request
+type Collector struct {
+	Daemon     *Daemon
+	TTL        time.Duration
+	collectors []prometheus.Collector
+
+	group   singleflight.Group
+	mu      sync.Mutex
+	fetched time.Time
+}
+
+// NewCollector returns a Collector which refreshes collectors from a fetch of
+// the daemon's trashcan before they are collected
+func NewCollector(d *Daemon, ttl time.Duration, collectors []prometheus.Collector) *Collector {
+	return &Collector{
+		Daemon:     d,
+		TTL:        ttl,
+		collectors: collectors,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, v := range c.collectors {
+		v.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector, fetching from the trashcan first
+// unless the last fetch is younger than TTL. Metrics carry the time of the
+// fetch they came from, so a cached fetch isn't taken as a new sample
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.Refresh()
+
+	c.mu.Lock()
+	fetched := c.fetched
+	c.mu.Unlock()
+
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		for _, v := range c.collectors {
+			v.Collect(metrics)
+		}
+		close(metrics)
+	}()
+	for m := range metrics {
+		ch <- prometheus.NewMetricWithTimestamp(fetched, m)
+	}
+}
+
+// Refresh fetches from the trashcan and hands the result to the daemon unless
+// the last fetch is younger than TTL. Callers arriving during a fetch wait for
+// it instead of starting their own
+func (c *Collector) Refresh() {
+	c.mu.Lock()
+	fresh := !c.fetched.IsZero() && time.Since(c.fetched) < c.TTL
+	c.mu.Unlock()
+	if fresh {
+		return
+	}
+
+	c.group.Do("fetch", func() (interface{}, error) {
+		start := time.Now()
+		data, err := c.Daemon.Trashcan.FetchRadioStatus()
+		c.Daemon.HandleScrape(&models.FastmileReturn{
+			Body:     data,
+			Error:    err,
+			Start:    start,
+			Duration: time.Since(start),
+		})
+
+		c.mu.Lock()
+		c.fetched = time.Now()
+		c.mu.Unlock()
+		return nil, nil
+	})
+}
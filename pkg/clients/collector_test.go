@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const fakeRadioStatus = `{
+	"connection_status": [{"ConnectionStatus": 1}],
+	"cellular_stats": [{"BytesReceived": 10, "BytesSent": 20}],
+	"cell_5G_stats_cfg": [{"stat": {"SNRCurrent": 9, "PhysicalCellID": "392", "Band": "n41"}}],
+	"cell_LTE_stats_cfg": [{"stat": {"SNRCurrent": 3, "PhysicalCellID": "121", "Band": "B66"}}]
+}`
+
+func TestCollector(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, fakeRadioStatus)
+	}))
+	defer srv.Close()
+
+	d, err := NewDaemon(srv.URL, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.MetricGroups = []string{metrics.Group5G}
+	c := NewCollector(d, time.Minute, d.TrashcanCollectors())
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	// concurrent scrapes share one fetch
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := registry.Gather(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expected 1 fetch for concurrent scrapes but got %d", n)
+	}
+	if v := testutil.ToFloat64(d.Metrics.Metric5GCurrentSNR); v != 9 {
+		t.Fatalf("Expected SNR 9 from the fetch but got %f", v)
+	}
+
+	// a cached fetch is reused until TTL passes, stamped with its fetch time
+	first, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expected the cached fetch reused but got %d fetches", n)
+	}
+	stamp := first[0].GetMetric()[0].GetTimestampMs()
+	if stamp == 0 || stamp != c.fetched.UnixMilli() {
+		t.Fatalf("Expected metrics stamped with the fetch at %d but got %d", c.fetched.UnixMilli(), stamp)
+	}
+	c.TTL = 0
+	registry.Gather()
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("Expected a new fetch after TTL but got %d fetches", n)
+	}
+	second, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second[0].GetMetric()[0].GetTimestampMs() <= stamp {
+		t.Fatalf("Expected a new fetch stamped later than %d", stamp)
+	}
+}
@@ -3,35 +3,97 @@ package clients
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/asciifaceman/gomo/pkg/cells"
 	"github.com/asciifaceman/gomo/pkg/metrics"
 	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/outage"
+	"github.com/asciifaceman/gomo/pkg/push"
+	"github.com/asciifaceman/gomo/pkg/slo"
+	"github.com/asciifaceman/gomo/pkg/speedtest"
+	"github.com/asciifaceman/gomo/pkg/status"
 	"github.com/asciifaceman/gomo/pkg/tmo"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
 const (
-	DefaultPort    = 2112
-	DefaultTimeout = 15
+	DefaultPort        = 2112
+	DefaultTimeout     = 15
+	DefaultMetricsPath = "/metrics"
+	DefaultStaleAfter  = 3
+	DefaultCacheTTL    = 5 * time.Second
+	// DefaultDiscoveryInterval is how often path MTU, the public address and
+	// the NAT type are checked, they rarely change
+	DefaultDiscoveryInterval = time.Hour
+	// DefaultCellHistorySaveInterval is how often the cell history is written
+	// to disk between handovers, sparing SD cards a write every scrape
+	DefaultCellHistorySaveInterval = 5 * time.Minute
 )
 
 // Daemon is the central daemon which surfaces metrics from the tmo trashcan
 type Daemon struct {
-	Logger                *zap.SugaredLogger
-	Server                *http.Server
-	Trashcan              *tmo.Trashcan
-	PollTimeout           time.Duration
-	FastmileReturnChannel chan *models.FastmileReturn
-	HttpErrorChannel      chan error
-	Signals               chan os.Signal
+	Logger                   *zap.SugaredLogger
+	Server                   *http.Server
+	Mux                      *http.ServeMux
+	Registry                 *prometheus.Registry
+	Metrics                  *metrics.Set
+	MetricsPath              string
+	MetricGroups             []string
+	RuntimeMetrics           bool
+	BandLabels               bool
+	LegacyMetricNames        bool
+	StaleAfter               int
+	OnDemand                 bool
+	CacheTTL                 time.Duration
+	Trashcan                 *tmo.Trashcan
+	PollInterval             time.Duration
+	AdaptivePoll             *AdaptivePoll
+	FastmileReturnChannel    chan *models.FastmileReturn
+	HttpErrorChannel         chan error
+	Signals                  chan os.Signal
+	CellHistory              *cells.History
+	CellHistorySaveInterval  time.Duration
+	cellsSaved               time.Time
+	Outages                  *outage.Detector
+	outagesSaved             time.Time
+	SLOs                     *slo.Tracker
+	Status                   *status.Status
+	StatusReturnChannel      chan *models.StatusReport
+	checking                 bool
+	DiscoveryInterval        time.Duration
+	DiscoveryReturnChannel   chan *models.StatusReport
+	discovering              bool
+	discoveryDue             bool
+	SpeedTest                *speedtest.SpeedTest
+	SpeedTestInterval        time.Duration
+	SpeedTestReturnChannel   chan *models.SpeedTestReport
+	BufferbloatInterval      time.Duration
+	BufferbloatReturnChannel chan *models.BufferbloatReport
+	loadTesting              bool
+	speedTestPending         bool
+	bufferbloatPending       bool
+	Push                     push.Sink
+	PushInterval             time.Duration
+	fetching                 bool
+	mu                       sync.Mutex
+	wanAddress               string
+	scraped                  bool
+	cell                     models.RadioTag
+	series                   map[*prometheus.GaugeVec][]string
+	apns                     map[[2]string]bool
+	scrapeFailures           int
 }
 
 // New returns a newly configured daemon ready to start
@@ -51,16 +113,34 @@ func NewDaemon(hostname string, port int, timeout int) (*Daemon, error) {
 		return nil, err
 	}
 
+	mux := http.NewServeMux()
+
 	g := &Daemon{
-		Logger:      logger.Sugar(),
-		Trashcan:    t,
-		PollTimeout: time.Duration(15),
+		Logger:       logger.Sugar(),
+		Trashcan:     t,
+		PollInterval: DefaultPollInterval,
+		Registry:     prometheus.NewRegistry(),
+		Metrics:      metrics.NewSet(),
+		Mux:          mux,
+		MetricsPath:  DefaultMetricsPath,
+		StaleAfter:   DefaultStaleAfter,
+		CacheTTL:     DefaultCacheTTL,
+
+		CellHistorySaveInterval: DefaultCellHistorySaveInterval,
+
+		DiscoveryInterval: DefaultDiscoveryInterval,
 		Server: &http.Server{
-			Addr: addr,
+			Addr:    addr,
+			Handler: mux,
 		},
-		FastmileReturnChannel: make(chan *models.FastmileReturn),
-		HttpErrorChannel:      make(chan error, 1),
-		Signals:               make(chan os.Signal, 1),
+		FastmileReturnChannel:    make(chan *models.FastmileReturn, 1),
+		HttpErrorChannel:         make(chan error, 1),
+		StatusReturnChannel:      make(chan *models.StatusReport, 1),
+		DiscoveryReturnChannel:   make(chan *models.StatusReport, 1),
+		SpeedTestReturnChannel:   make(chan *models.SpeedTestReport, 1),
+		BufferbloatReturnChannel: make(chan *models.BufferbloatReport, 1),
+		Signals:                  make(chan os.Signal, 1),
+		series:                   make(map[*prometheus.GaugeVec][]string),
 	}
 
 	signal.Notify(g.Signals, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -68,19 +148,209 @@ func NewDaemon(hostname string, port int, timeout int) (*Daemon, error) {
 	return g, nil
 }
 
-// RegisterMetrics registers various metrics with prometheus_client to be surfaced
+// RegisterMetrics registers the metrics of every enabled group that applies
+// to the configured checks with the daemon's registry
 func (d *Daemon) RegisterMetrics() {
-	for _, v := range metrics.Metrics5G {
-		prometheus.MustRegister(v)
+	if d.RuntimeMetrics {
+		d.Registry.MustRegister(collectors.NewGoCollector())
+		d.Registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 	}
 
-	for _, v := range metrics.MetricsLTE {
-		prometheus.MustRegister(v)
+	trashcan := d.TrashcanCollectors()
+	if d.OnDemand {
+		d.Registry.MustRegister(NewCollector(d, d.CacheTTL, trashcan))
+	} else {
+		d.Registry.MustRegister(trashcan...)
+		if d.GroupEnabled(metrics.GroupScrape) {
+			d.Registry.MustRegister(d.Metrics.MetricScrapePollInterval)
+		}
 	}
 
-	for _, v := range metrics.MetricsMisc {
-		prometheus.MustRegister(v)
+	if d.Status != nil {
+		if d.GroupEnabled(metrics.GroupPing) {
+			for _, v := range d.Metrics.MetricsPing {
+				d.Registry.MustRegister(v)
+			}
+
+			for _, v := range d.Metrics.MetricsPingHistograms {
+				d.Registry.MustRegister(v)
+			}
+
+			for _, v := range d.Metrics.MetricsPingCounters {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if d.GroupEnabled(metrics.GroupDNS) {
+			for _, v := range d.Metrics.MetricsDNS {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if d.Status.Gateway != "" && d.GroupEnabled(metrics.GroupLink) {
+			for _, v := range d.Metrics.MetricsLink {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if len(d.Status.MTUTargets) > 0 && d.GroupEnabled(metrics.GroupMTU) {
+			for _, v := range d.Metrics.MetricsMTU {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if d.Status.EchoURL != "" && d.GroupEnabled(metrics.GroupCGNAT) {
+			for _, v := range d.Metrics.MetricsCGNAT {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if len(d.Status.Families) > 0 && d.GroupEnabled(metrics.GroupFamily) {
+			for _, v := range d.Metrics.MetricsFamily {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if len(d.Status.WANs) > 0 && d.GroupEnabled(metrics.GroupWAN) {
+			for _, v := range d.Metrics.MetricsWAN {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if len(d.Status.STUNServers) > 0 && d.GroupEnabled(metrics.GroupNAT) {
+			for _, v := range d.Metrics.MetricsNAT {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if d.GroupEnabled(metrics.GroupTCP) {
+			for _, v := range d.Metrics.MetricsTCP {
+				d.Registry.MustRegister(v)
+			}
+		}
+
+		if d.GroupEnabled(metrics.GroupHTTP) {
+			for _, v := range d.Metrics.MetricsHTTP {
+				d.Registry.MustRegister(v)
+			}
+		}
+	}
+
+	if d.Outages != nil && d.GroupEnabled(metrics.GroupOutage) {
+		for _, v := range d.Metrics.MetricsOutage {
+			d.Registry.MustRegister(v)
+		}
+
+		for _, v := range d.Metrics.MetricsOutageCounters {
+			d.Registry.MustRegister(v)
+		}
+
+		// start every classification at zero so rate() and increase() work
+		// from the first outage onwards
+		for _, state := range outage.States {
+			if state == outage.StateUp {
+				continue
+			}
+			for _, v := range d.Metrics.MetricsOutageCounters {
+				v.WithLabelValues(state)
+			}
+		}
+	}
+
+	if d.Status != nil && d.SLOs != nil && d.GroupEnabled(metrics.GroupSLO) {
+		for _, v := range d.Metrics.MetricsSLO {
+			d.Registry.MustRegister(v)
+		}
+	}
+
+	if d.SpeedTest != nil && d.SpeedTestInterval > 0 && d.GroupEnabled(metrics.GroupSpeedTest) {
+		for _, v := range d.Metrics.MetricsSpeedTest {
+			d.Registry.MustRegister(v)
+		}
+	}
+
+	if d.SpeedTest != nil && d.Status != nil && d.BufferbloatInterval > 0 && d.GroupEnabled(metrics.GroupBufferbloat) {
+		for _, v := range d.Metrics.MetricsBufferbloat {
+			d.Registry.MustRegister(v)
+		}
+	}
+
+	if d.Push != nil && d.GroupEnabled(metrics.GroupPush) {
+		for _, v := range d.Metrics.MetricsPush {
+			d.Registry.MustRegister(v)
+		}
+	}
+}
+
+// TrashcanCollectors returns the metrics of every enabled group which are
+// updated from scrapes of the trashcan
+func (d *Daemon) TrashcanCollectors() []prometheus.Collector {
+	var ret []prometheus.Collector
+
+	for _, g := range d.radioMetrics() {
+		if !d.GroupEnabled(g.Group) {
+			continue
+		}
+		if byCell, ok := d.Metrics.RadioMetricsByCell[g.Metric]; ok && d.BandLabels {
+			ret = append(ret, byCell)
+			continue
+		}
+		ret = append(ret, g.Metric)
+	}
+
+	if d.GroupEnabled(metrics.Group5G) {
+		ret = append(ret, d.Metrics.Metric5GCellInfo)
+	}
+
+	if d.GroupEnabled(metrics.GroupLTE) {
+		ret = append(ret, d.Metrics.MetricLTECellInfo)
+	}
+
+	if d.GroupEnabled(metrics.GroupAPN) {
+		for _, v := range d.Metrics.MetricsAPN {
+			ret = append(ret, v)
+		}
+	}
+
+	if d.GroupEnabled(metrics.GroupSignal) {
+		for _, h := range d.Metrics.RadioHistograms {
+			ret = append(ret, h.Histogram)
+		}
+	}
+
+	if d.GroupEnabled(metrics.GroupScrape) {
+		for _, v := range d.Metrics.MetricsScrape {
+			ret = append(ret, v)
+		}
+
+		for _, v := range d.Metrics.MetricsScrapeCounters {
+			ret = append(ret, v)
+		}
+
+		ret = append(ret, d.Metrics.MetricScrapeDuration)
+	}
+
+	return ret
+}
+
+// RegisterHandlers mounts the metrics and health endpoints on the daemon's mux
+func (d *Daemon) RegisterHandlers() {
+	d.Mux.Handle(d.MetricsPath, promhttp.HandlerFor(d.Registry, promhttp.HandlerOpts{}))
+	d.Mux.HandleFunc("/health", d.Hello)
+}
+
+// GroupEnabled returns true if a metric group should be exported, every group
+// is when MetricGroups is empty
+func (d *Daemon) GroupEnabled(group string) bool {
+	if len(d.MetricGroups) == 0 {
+		return true
+	}
+	for _, g := range d.MetricGroups {
+		if g == group {
+			return true
+		}
 	}
+	return false
 }
 
 func (d *Daemon) Run() error {
@@ -88,19 +358,60 @@ func (d *Daemon) Run() error {
 
 	var wg sync.WaitGroup
 
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	var speedTestTicker <-chan time.Time
+	if d.SpeedTest != nil && d.SpeedTestInterval > 0 {
+		t := time.NewTicker(d.SpeedTestInterval)
+		defer t.Stop()
+		speedTestTicker = t.C
+	}
+
+	var bufferbloatTicker <-chan time.Time
+	if d.SpeedTest != nil && d.Status != nil && d.BufferbloatInterval > 0 {
+		t := time.NewTicker(d.BufferbloatInterval)
+		defer t.Stop()
+		bufferbloatTicker = t.C
+	}
+
+	// discovery runs once at startup then on its own slow schedule
+	var discoveryTicker <-chan time.Time
+	if d.Status != nil && d.DiscoveryInterval > 0 {
+		t := time.NewTicker(d.DiscoveryInterval)
+		defer t.Stop()
+		discoveryTicker = t.C
+		d.discoveryDue = true
+	}
+
+	interval := d.PollInterval
+	if d.AdaptivePoll != nil {
+		interval = d.AdaptivePoll.Current()
+	}
+	poll := time.NewTicker(interval)
+	defer poll.Stop()
+	d.setPollInterval(interval)
+
 	d.Logger.Info("Starting webserver...")
 
-	http.Handle("/metrics", promhttp.Handler())
-	http.HandleFunc("/health", d.Hello)
+	d.RegisterHandlers()
 
 	go d.BackgroundHTTPServer()
 
 	d.Logger.Info(fmt.Sprintf("Listening on %s, entering runtime loop", d.Server.Addr))
 
+	if d.Push != nil {
+		wg.Add(1)
+		go d.PushAsync(runCtx, &wg)
+	}
+
 	for {
 		select {
 		case <-d.Signals:
 			d.Logger.Info("Received exit signal, shutting down")
+			stop()
+			d.saveCellHistory()
+			d.saveOutages()
 			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 			err := d.Server.Shutdown(ctx)
 			cancel()
@@ -110,47 +421,852 @@ func (d *Daemon) Run() error {
 			d.Logger.Info("Waiting on subroutines...")
 			wg.Wait()
 			return err
-		case <-time.After(d.PollTimeout * time.Second):
-			d.Logger.Info("Scraping data...")
+		case <-poll.C:
+			// on demand the trashcan is fetched when prometheus scrapes, and a
+			// slow trashcan is not asked again until it has answered
+			if !d.OnDemand && !d.fetching {
+				d.Logger.Info("Scraping data...")
+				d.fetching = true
+				wg.Add(1)
+				go d.Trashcan.FetchRadioStatusAsync(&wg, d.FastmileReturnChannel)
+			}
+
+			// status checks would both skew and be skewed by a saturated link,
+			// and hold off while a load test waits for the last checks
+			if d.Status != nil && !d.checking && !d.loadTesting && !d.speedTestPending && !d.bufferbloatPending {
+				d.checking = true
+				wg.Add(1)
+				go d.StatusAsync(&wg)
+			}
+
+			// CGNAT detection needs the WAN address of the first scrape
+			d.mu.Lock()
+			scraped := d.scraped
+			d.mu.Unlock()
+			if d.discoveryDue && scraped && !d.discovering && !d.loadTesting && !d.speedTestPending && !d.bufferbloatPending {
+				d.Logger.Info("Running discovery checks...")
+				d.discoveryDue = false
+				d.discovering = true
+				wg.Add(1)
+				go d.DiscoverAsync(&wg)
+			}
+
+		case <-discoveryTicker:
+			d.discoveryDue = true
+		case <-speedTestTicker:
+			// a tick during status checks waits for them rather than a whole interval
+			d.speedTestPending = true
+			if d.checking {
+				d.Logger.Info("Status checks still running, speed test will start once they finish")
+			}
+			d.startLoadTest(runCtx, &wg)
+
+		case <-bufferbloatTicker:
+			d.bufferbloatPending = true
+			if d.checking {
+				d.Logger.Info("Status checks still running, bufferbloat test will start once they finish")
+			}
+			d.startLoadTest(runCtx, &wg)
+
+		case report := <-d.BufferbloatReturnChannel:
+			d.loadTesting = false
+			d.Logger.Infow("Bufferbloat test complete, updating metrics", "grade", report.Grade)
+			d.UpdateBufferbloatMetrics(report)
+			d.startLoadTest(runCtx, &wg)
+
+		case report := <-d.SpeedTestReturnChannel:
+			d.loadTesting = false
+			d.Logger.Infow("Speed test complete, updating metrics",
+				"download_bps", throughputOf(report.Download),
+				"upload_bps", throughputOf(report.Upload),
+			)
+			d.UpdateSpeedTestMetrics(report)
+			d.writePoints(speedTestPoints(report))
+			d.startLoadTest(runCtx, &wg)
 
-			go d.Trashcan.FetchRadioStatusAsync(&wg, d.FastmileReturnChannel)
-			wg.Add(1)
 		case err := <-d.HttpErrorChannel:
+			stop()
 			wg.Wait()
 			return err
 
+		case report := <-d.DiscoveryReturnChannel:
+			d.discovering = false
+			d.startLoadTest(runCtx, &wg)
+			d.Logger.Info("Received discovery checks, updating metrics")
+			d.UpdateMTUMetrics(report.MTU)
+			d.mu.Lock()
+			wanAddress := d.wanAddress
+			d.mu.Unlock()
+			if report.Echo != nil && wanAddress != "" {
+				d.UpdateCGNATMetrics(status.DetectCGNAT(wanAddress, report.Echo))
+			}
+			if report.NAT != nil {
+				d.UpdateNATMetrics(report.NAT)
+			}
+
+		case report := <-d.StatusReturnChannel:
+			d.checking = false
+			d.startLoadTest(runCtx, &wg)
+			d.Logger.Info("Received status checks, updating metrics")
+			// the attached cell comes from the trashcan
+			d.mu.Lock()
+			cell := d.cell
+			d.mu.Unlock()
+			d.UpdatePingMetrics(report.Pings)
+			d.writePoints(pingPoints(report.Pings, report.Start, cell))
+			d.UpdateDNSMetrics(report.DNS)
+			d.UpdateTCPMetrics(report.TCP)
+			d.UpdateHTTPMetrics(report.HTTP)
+			d.UpdateLinkMetrics(report)
+			d.UpdateFamilyMetrics(report.Families)
+			d.UpdateWANMetrics(report.WANs)
+			if d.Outages != nil {
+				d.Outages.ObserveInternet(report)
+			}
+			if d.SLOs != nil {
+				d.UpdateSLOs(report)
+			}
+
 		case ret := <-d.FastmileReturnChannel:
-			if ret.Error != nil {
-				d.Logger.Errorw("Errored scraping trashcan", "error", ret.Error.Error())
+			d.fetching = false
+			d.HandleScrape(ret)
+
+			if d.AdaptivePoll != nil {
+				down := d.Outages != nil && d.Outages.State() != outage.StateUp
+				next, reason := d.AdaptivePoll.Next(ret, down)
+				if next != interval {
+					d.Logger.Infow("Changing poll interval", "interval", next.String(), "reason", reason)
+					interval = next
+					poll.Reset(interval)
+					d.setPollInterval(interval)
+				}
+			}
+		}
+	}
+
+}
+
+// setPollInterval exports the interval the trashcan is polled at
+func (d *Daemon) setPollInterval(interval time.Duration) {
+	if !d.OnDemand {
+		d.Metrics.MetricScrapePollInterval.Set(interval.Seconds())
+	}
+}
+
+// HandleScrape updates the scrape health, outage, radio and cell history from a
+// scrape of the trashcan, whether it came from the poll loop or a Collector.
+// The cell history is written outside the lock, on a handover or once
+// CellHistorySaveInterval has passed
+func (d *Daemon) HandleScrape(ret *models.FastmileReturn) {
+	if d.handleScrape(ret) {
+		d.saveCellHistory()
+	}
+}
+
+// handleScrape is HandleScrape under the lock, returning true when the cell
+// history is due to be saved
+func (d *Daemon) handleScrape(ret *models.FastmileReturn) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.UpdateScrapeMetrics(ret)
+	if d.Outages != nil {
+		d.UpdateOutage(ret)
+	}
+	if ret.Error != nil {
+		d.Logger.Errorw("Errored scraping trashcan", "error", ret.Error.Error())
+		return false
+	}
+	if ret.Body == nil {
+		d.Logger.Errorw("received empty body without error")
+		return false
+	}
+	d.Logger.Info("Received fastmile data, updating metrics")
+	d.scraped = true
+
+	if len(ret.Body.ApCfg) > 0 {
+		d.wanAddress = ret.Body.ApCfg[0].IPV4
+	}
+
+	d.UpdateRadioMetrics(ret)
+	d.cell = attachedCell(ret)
+	d.writePoints(radioPoints(ret, d.cell))
+
+	if d.CellHistory == nil {
+		return false
+	}
+	now := time.Now()
+	handover := d.CellHistory.Observe(ret.Body, now)
+	if !handover && now.Sub(d.cellsSaved) < d.CellHistorySaveInterval {
+		return false
+	}
+	d.cellsSaved = now
+	return true
+}
+
+// saveCellHistory writes the cell history to disk if one is kept
+func (d *Daemon) saveCellHistory() {
+	if d.CellHistory == nil {
+		return
+	}
+	if err := d.CellHistory.Save(); err != nil {
+		d.Logger.Errorw("Failed to save cell history", "error", err)
+	}
+}
+
+// DiscoverAsync is for running in a goroutine, runs the discovery checks and
+// returns the report on DiscoveryReturnChannel
+func (d *Daemon) DiscoverAsync(wg *sync.WaitGroup) {
+	defer wg.Done()
+	d.DiscoveryReturnChannel <- d.Status.Discover()
+}
+
+// StatusAsync is for running in a goroutine, runs the configured status checks
+// and returns the report on StatusReturnChannel
+func (d *Daemon) StatusAsync(wg *sync.WaitGroup) {
+	defer wg.Done()
+	d.StatusReturnChannel <- d.Status.Run()
+}
+
+// UpdateScrapeMetrics records the health of a scrape of the trashcan. Once
+// StaleAfter scrapes in a row have failed the radio metrics are cleared so
+// dashboards show a gap instead of the last values seen
+func (d *Daemon) UpdateScrapeMetrics(ret *models.FastmileReturn) {
+	d.Metrics.MetricScrapeDuration.Observe(ret.Duration.Seconds())
+
+	if ret.Error != nil || ret.Body == nil {
+		d.scrapeFailures++
+		d.Metrics.MetricsScrapeCounters["failures"].Inc()
+		d.Metrics.MetricsScrape["up"].Set(0)
+		d.Metrics.MetricsScrape["consecutive_failures"].Set(float64(d.scrapeFailures))
+		if d.StaleAfter > 0 && d.scrapeFailures == d.StaleAfter {
+			d.Logger.Warnw("Trashcan unreachable, clearing radio metrics", "failures", d.scrapeFailures)
+			d.ClearRadioMetrics()
+		}
+		return
+	}
+
+	d.scrapeFailures = 0
+	d.Metrics.MetricsScrapeCounters["success"].Inc()
+	d.Metrics.MetricsScrape["up"].Set(1)
+	d.Metrics.MetricsScrape["consecutive_failures"].Set(0)
+	d.Metrics.MetricsScrape["last_success"].Set(float64(time.Now().Unix()))
+}
+
+// ClearRadioMetrics sets every radio gauge to NaN and removes the labeled
+// cell and APN series until the next successful scrape
+func (d *Daemon) ClearRadioMetrics() {
+	for _, g := range d.radioMetrics() {
+		g.Metric.Set(math.NaN())
+	}
+	for _, v := range d.Metrics.RadioMetricsByCell {
+		d.clearSeries(v)
+	}
+	d.clearSeries(d.Metrics.Metric5GCellInfo)
+	d.clearSeries(d.Metrics.MetricLTECellInfo)
+	d.updateAPNs(nil)
+	d.cell = models.RadioTag{}
+}
+
+// UpdateRadioMetrics sets every metric in d.Metrics.RadioMetrics, the cell info
+// and the APN states from a scrape and samples the signal histograms. Labeled
+// series of the previous cell are removed on handover
+func (d *Daemon) UpdateRadioMetrics(ret *models.FastmileReturn) {
+	cells := make(map[string][]string, 2)
+	if len(ret.Body.Cell5GStats) > 0 && ret.Body.Cell5GStats[0] != nil && ret.Body.Cell5GStats[0].Stat != nil {
+		nr := ret.Stat5G()
+		cells[metrics.Group5G] = []string{nr.Band, nr.PhysicalCellID}
+		d.setCellInfo(d.Metrics.Metric5GCellInfo, nr.Band, nr.PhysicalCellID, nr.DownlinkNRARFCN)
+	}
+	if len(ret.Body.CellLTEStats) > 0 && ret.Body.CellLTEStats[0] != nil && ret.Body.CellLTEStats[0].Stat != nil {
+		lte := ret.StatLTE()
+		cells[metrics.GroupLTE] = []string{lte.Band, lte.PhysicalCellID}
+		d.setCellInfo(d.Metrics.MetricLTECellInfo, lte.Band, lte.PhysicalCellID, lte.DownlinkEarfcn)
+	}
+
+	for _, g := range d.radioMetrics() {
+		value, ok := g.Value(ret.Body)
+		if !ok {
+			continue
+		}
+		if vec, ok := d.Metrics.RadioMetricsByCell[g.Metric]; ok && d.BandLabels {
+			if cell := cells[g.Group]; cell != nil && cell[1] != "" {
+				d.setSeries(vec, value, cell...)
+			} else {
+				d.clearSeries(vec)
+			}
+			continue
+		}
+		g.Metric.Set(value)
+	}
+
+	// signal is only sampled while attached, a detached radio reports nothing useful
+	for _, h := range d.Metrics.RadioHistograms {
+		cell := cells[h.Radio]
+		if cell == nil || cell[1] == "" {
+			continue
+		}
+		if value, ok := h.Value(ret.Body); ok {
+			h.Histogram.WithLabelValues(cell[0]).Observe(value)
+		}
+	}
+
+	d.updateAPNs(ret.Body.ApCfg)
+}
+
+// radioMetrics returns the metrics set from trashcan scrapes, including the
+// legacy names when enabled
+func (d *Daemon) radioMetrics() []metrics.RadioMetric {
+	if !d.LegacyMetricNames {
+		return d.Metrics.RadioMetrics
+	}
+	ret := make([]metrics.RadioMetric, 0, len(d.Metrics.RadioMetrics)+len(d.Metrics.LegacyRadioMetrics))
+	ret = append(ret, d.Metrics.RadioMetrics...)
+	return append(ret, d.Metrics.LegacyRadioMetrics...)
+}
+
+// updateAPNs sets the state of every configured APN and removes the series of
+// APNs no longer reported
+func (d *Daemon) updateAPNs(apns []*models.ApnCfg) {
+	seen := make(map[[2]string]bool, len(apns))
+	for _, apn := range apns {
+		if apn == nil {
+			continue
+		}
+		labels := [2]string{apn.APN, apn.ServiceType}
+		seen[labels] = true
+		d.Metrics.MetricsAPN["enabled"].WithLabelValues(labels[:]...).Set(float64(apn.Enable))
+		d.Metrics.MetricsAPN["connection_state"].WithLabelValues(labels[:]...).Set(float64(apn.ConnectionState))
+	}
+	for labels := range d.apns {
+		if !seen[labels] {
+			for _, v := range d.Metrics.MetricsAPN {
+				v.DeleteLabelValues(labels[:]...)
+			}
+		}
+	}
+	d.apns = seen
+}
+
+// setCellInfo sets a cell info metric to the attached cell, or removes it
+// when the radio is not attached to any
+func (d *Daemon) setCellInfo(vec *prometheus.GaugeVec, band string, pci string, arfcn float64) {
+	if pci == "" {
+		d.clearSeries(vec)
+		return
+	}
+	d.setSeries(vec, 1, band, pci, strconv.FormatFloat(arfcn, 'f', -1, 64))
+}
+
+// setSeries sets the only series of vec, deleting the previous one when its
+// labels changed so a handover doesn't leave the old cell's series behind
+func (d *Daemon) setSeries(vec *prometheus.GaugeVec, value float64, labels ...string) {
+	if prev, ok := d.series[vec]; ok && strings.Join(prev, "\x00") != strings.Join(labels, "\x00") {
+		vec.DeleteLabelValues(prev...)
+	}
+	d.series[vec] = labels
+	vec.WithLabelValues(labels...).Set(value)
+}
+
+// clearSeries deletes the series last set on vec with setSeries
+func (d *Daemon) clearSeries(vec *prometheus.GaugeVec) {
+	if prev, ok := d.series[vec]; ok {
+		vec.DeleteLabelValues(prev...)
+		delete(d.series, vec)
+	}
+}
+
+// PushAsync is for running in a goroutine, pushes the registry to the push
+// sink every PushInterval until ctx is done, then persists anything unsent
+func (d *Daemon) PushAsync(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	t := time.NewTicker(d.PushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := d.Push.Close(); err != nil {
+				d.Logger.Errorw("Failed to save unsent metrics", "error", err)
+			}
+			return
+		case at := <-t.C:
+			err := d.Push.Push(ctx, at)
+			stats := d.Push.Stats()
+			d.UpdatePushMetrics(stats)
+			if err != nil && ctx.Err() == nil {
+				d.Logger.Warnw("Failed to push metrics", "error", err, "buffered_samples", stats.Buffered)
+			}
+		}
+	}
+}
+
+// UpdatePushMetrics exports the running totals of the push sink
+func (d *Daemon) UpdatePushMetrics(stats push.Stats) {
+	d.Metrics.MetricsPush["sent"].Set(float64(stats.Sent))
+	d.Metrics.MetricsPush["failed"].Set(float64(stats.Failed))
+	d.Metrics.MetricsPush["dropped"].Set(float64(stats.Dropped))
+	d.Metrics.MetricsPush["buffered"].Set(float64(stats.Buffered))
+	if !stats.LastSent.IsZero() {
+		d.Metrics.MetricsPush["last_success"].Set(float64(stats.LastSent.Unix()))
+	}
+}
+
+// UpdateOutage feeds a scrape to the outage detector, logs and exports any
+// recorded state change and persists the outage log on a state change, every
+// scrape during an outage and every outage.HeartbeatInterval otherwise
+func (d *Daemon) UpdateOutage(ret *models.FastmileReturn) {
+	now := time.Now()
+	t := d.Outages.ObserveGateway(ret, now)
+
+	if t != nil {
+		if t.Closed != nil {
+			d.Logger.Infow("Outage ended",
+				"classification", t.Closed.Classification,
+				"duration", t.Closed.Duration(now).String(),
+			)
+			d.Metrics.MetricsOutageCounters["downtime"].WithLabelValues(t.Closed.Classification).Add(t.Closed.Duration(now).Seconds())
+		}
+		if t.Opened != nil {
+			d.Logger.Warnw("Outage detected",
+				"classification", t.Opened.Classification,
+				"reason", t.Opened.Reason,
+				"since", t.Opened.Start,
+			)
+			d.Metrics.MetricsOutageCounters["outages"].WithLabelValues(t.Opened.Classification).Inc()
+		}
+	}
+
+	state := d.Outages.State()
+	for _, s := range outage.States {
+		val := 0.0
+		if s == state {
+			val = 1
+		}
+		d.Metrics.MetricsOutage["state"].WithLabelValues(s).Set(val)
+	}
+
+	current := 0.0
+	if o := d.Outages.Current(); o != nil {
+		current = o.Duration(now).Seconds()
+	}
+	d.Metrics.MetricsOutage["current_duration"].WithLabelValues().Set(current)
+
+	// ongoing outages are saved every scrape so a restart knows when they were
+	// last seen, and uptime on a heartbeat so it knows when monitoring stopped
+	if t != nil || state != outage.StateUp || now.Sub(d.outagesSaved) >= outage.HeartbeatInterval {
+		d.outagesSaved = now
+		d.saveOutages()
+	}
+}
+
+// saveOutages writes the outage log to disk if one is kept
+func (d *Daemon) saveOutages() {
+	if d.Outages == nil {
+		return
+	}
+	if err := d.Outages.Save(); err != nil {
+		d.Logger.Errorw("Failed to save outage log", "error", err)
+	}
+}
+
+// UpdateSLOs records a status report against every SLO, persists the events
+// and exports each SLO's compliance and error budget
+func (d *Daemon) UpdateSLOs(report *models.StatusReport) {
+	now := time.Now()
+	d.SLOs.Observe(report, now)
+	if err := d.SLOs.Save(); err != nil {
+		d.Logger.Errorw("Failed to save SLO history", "error", err)
+	}
+
+	for _, s := range d.SLOs.Report(now) {
+		name := s.Objective.Name
+		met := 0.0
+		if s.Met {
+			met = 1
+		}
+		d.Metrics.MetricsSLO["objective"].WithLabelValues(name).Set(s.Objective.Objective)
+		d.Metrics.MetricsSLO["compliance"].WithLabelValues(name).Set(s.Compliance)
+		d.Metrics.MetricsSLO["budget_remaining"].WithLabelValues(name).Set(s.BudgetRemaining)
+		d.Metrics.MetricsSLO["burn_rate"].WithLabelValues(name).Set(s.BurnRate)
+		d.Metrics.MetricsSLO["met"].WithLabelValues(name).Set(met)
+		d.Metrics.MetricsSLO["events"].WithLabelValues(name).Set(float64(s.Total))
+		d.Metrics.MetricsSLO["good_events"].WithLabelValues(name).Set(float64(s.Good))
+	}
+}
+
+// UpdatePingMetrics sets the per target ping gauges from a set of reports.
+// Targets without an address in the family they were probed over are skipped
+func (d *Daemon) UpdatePingMetrics(reports []*models.PingReportReturn) {
+	for _, r := range reports {
+		if r.NoRecord {
+			continue
+		}
+
+		if r.Body != nil {
+			d.Metrics.MetricsPing["loss_ratio"].WithLabelValues(r.Hostname, r.Family).Set(r.Body.PacketLoss / 100)
+			d.Metrics.MetricsPing["loss_streak"].WithLabelValues(r.Hostname, r.Family).Set(float64(r.Body.LossStreak))
+			d.Metrics.MetricsPing["loss_streak_max"].WithLabelValues(r.Hostname, r.Family).Set(float64(r.Body.LossStreakMax))
+
+			// a check that failed outright reports every packet it would have
+			// sent as lost, so loss rates don't read 0% during an outage
+			d.Metrics.MetricsPingCounters["packets_sent"].WithLabelValues(r.Hostname, r.Family).Add(float64(r.Body.PacketsSent))
+			if lost := r.Body.PacketsSent - r.Body.PacketsRecv; lost > 0 {
+				d.Metrics.MetricsPingCounters["packets_lost"].WithLabelValues(r.Hostname, r.Family).Add(float64(lost))
+			}
+		}
+
+		if r.Error != nil {
+			d.Logger.Errorw("Errored pinging target", "target", r.Hostname, "family", r.Family, "error", r.Error.Error())
+			d.Metrics.MetricsPing["up"].WithLabelValues(r.Hostname, r.Family).Set(0)
+			continue
+		}
+
+		d.Metrics.MetricsPing["up"].WithLabelValues(r.Hostname, r.Family).Set(1)
+		d.Metrics.MetricsPing["rtt_min"].WithLabelValues(r.Hostname, r.Family).Set(r.Body.MinResponseTime.Seconds())
+		d.Metrics.MetricsPing["rtt_avg"].WithLabelValues(r.Hostname, r.Family).Set(r.Body.AvgResponseTime.Seconds())
+		d.Metrics.MetricsPing["rtt_max"].WithLabelValues(r.Hostname, r.Family).Set(r.Body.MaxResponseTime.Seconds())
+		d.Metrics.MetricsPing["jitter"].WithLabelValues(r.Hostname, r.Family).Set(r.Body.Jitter.Seconds())
+
+		for _, rtt := range r.Body.Rtts {
+			d.Metrics.MetricsPingHistograms["rtt"].WithLabelValues(r.Hostname, r.Family).Observe(rtt.Seconds())
+		}
+	}
+}
+
+// UpdateLinkMetrics sets the LAN and WAN segment gauges and the verdict from
+// the gateway and internet pings of a status report
+func (d *Daemon) UpdateLinkMetrics(report *models.StatusReport) {
+	if report.Gateway == nil {
+		return
+	}
+
+	if r := report.Gateway; r.Error != nil {
+		d.Logger.Errorw("Errored pinging gateway", "target", r.Hostname, "error", r.Error.Error())
+	}
+
+	if split := report.Split; split != nil {
+		for segment, stats := range map[string]models.SegmentStats{"lan": split.LAN, "wan": split.WAN} {
+			d.Metrics.MetricsLink["loss"].WithLabelValues(segment).Set(stats.Loss)
+			d.Metrics.MetricsLink["latency"].WithLabelValues(segment).Set(stats.Latency.Seconds())
+			d.Metrics.MetricsLink["jitter"].WithLabelValues(segment).Set(stats.Jitter.Seconds())
+		}
+
+		for _, v := range status.Verdicts {
+			val := 0.0
+			if v == split.Verdict {
+				val = 1
+			}
+			d.Metrics.MetricsLink["verdict"].WithLabelValues(v).Set(val)
+		}
+
+		if split.Verdict != status.VerdictHealthy {
+			d.Logger.Warnw("Connection degraded", "verdict", split.Verdict, "reason", split.Reason)
+		}
+		return
+	}
+
+	// without internet pings only the local segment can be measured
+	if body := report.Gateway.Body; body != nil {
+		d.Metrics.MetricsLink["loss"].WithLabelValues("lan").Set(body.PacketLoss / 100)
+		d.Metrics.MetricsLink["latency"].WithLabelValues("lan").Set(body.AvgResponseTime.Seconds())
+		d.Metrics.MetricsLink["jitter"].WithLabelValues("lan").Set(body.Jitter.Seconds())
+	}
+}
+
+// UpdateMTUMetrics sets the per target path MTU gauges from a set of reports
+func (d *Daemon) UpdateMTUMetrics(reports []*models.MTUReport) {
+	for _, r := range reports {
+		if r.Error != "" {
+			d.Logger.Errorw("Errored discovering path MTU", "target", r.Target, "error", r.Error)
+		}
+		d.Metrics.MetricsMTU["path"].WithLabelValues(r.Target).Set(float64(r.MTU))
+	}
+}
+
+// UpdateNATMetrics sets the NAT type and per server STUN gauges from a report,
+// replacing the behavior labels of previous checks
+func (d *Daemon) UpdateNATMetrics(report *models.NATReport) {
+	for _, s := range report.Servers {
+		up := 1.0
+		if s.Error != "" {
+			up = 0
+			d.Logger.Errorw("Errored querying STUN server", "server", s.Server, "error", s.Error)
+		}
+		d.Metrics.MetricsNAT["stun_up"].WithLabelValues(s.Server).Set(up)
+		d.Metrics.MetricsNAT["stun_rtt"].WithLabelValues(s.Server).Set(s.RTT.Seconds())
+	}
+
+	if report.Error != "" {
+		d.Logger.Errorw("Errored detecting NAT type", "error", report.Error)
+	}
+
+	d.Metrics.MetricsNAT["info"].Reset()
+	d.Metrics.MetricsNAT["info"].WithLabelValues(report.Mapping, report.Filtering, report.Type, report.Console).Set(1)
+
+	preserved := 0.0
+	if report.PortPreserved {
+		preserved = 1
+	}
+	d.Metrics.MetricsNAT["port_preserved"].WithLabelValues().Set(preserved)
+}
+
+// UpdateCGNATMetrics sets the CGNAT gauges from a report, replacing the
+// address labels of previous checks
+func (d *Daemon) UpdateCGNATMetrics(report *models.CGNATReport) {
+	if report.Error != "" {
+		d.Logger.Errorw("Errored detecting CGNAT", "error", report.Error)
+		return
+	}
+
+	for name, val := range map[string]bool{
+		"detected":     report.CGNAT,
+		"shared_space": report.SharedSpace,
+		"translated":   report.Translated,
+	} {
+		gauge := 0.0
+		if val {
+			gauge = 1
+		}
+		d.Metrics.MetricsCGNAT[name].WithLabelValues().Set(gauge)
+	}
+
+	d.Metrics.MetricsCGNAT["info"].Reset()
+	d.Metrics.MetricsCGNAT["info"].WithLabelValues(report.WANAddress, report.PublicAddress).Set(1)
+}
+
+// UpdateDNSMetrics sets the per resolver and name DNS gauges from a set of reports
+func (d *Daemon) UpdateDNSMetrics(reports []*models.DNSReport) {
+	for _, r := range reports {
+		if r.NoRecord {
+			continue
+		}
+		if r.Failures > 0 {
+			d.Logger.Errorw("Errored resolving name", "resolver", r.Resolver, "name", r.Name, "family", r.Family, "error", r.Error)
+		}
+
+		d.Metrics.MetricsDNS["latency_avg"].WithLabelValues(r.Resolver, r.Name, r.Family).Set(r.AvgLatency.Seconds())
+		d.Metrics.MetricsDNS["latency_max"].WithLabelValues(r.Resolver, r.Name, r.Family).Set(r.MaxLatency.Seconds())
+		d.Metrics.MetricsDNS["failure_ratio"].WithLabelValues(r.Resolver, r.Name, r.Family).Set(r.FailureRate)
+		mismatch := 0.0
+		if r.Mismatch {
+			mismatch = 1
+		}
+		d.Metrics.MetricsDNS["mismatch"].WithLabelValues(r.Resolver, r.Name, r.Family).Set(mismatch)
+	}
+}
+
+// UpdateTCPMetrics sets the per target TCP gauges from a set of reports
+func (d *Daemon) UpdateTCPMetrics(reports []*models.TCPReport) {
+	for _, r := range reports {
+		if r.NoRecord {
+			continue
+		}
+
+		up := 1.0
+		if !r.Up {
+			up = 0
+			d.Logger.Errorw("Errored connecting to target", "target", r.Target, "family", r.Family, "error", r.Error)
+		}
+
+		d.Metrics.MetricsTCP["up"].WithLabelValues(r.Target, r.Family).Set(up)
+		d.Metrics.MetricsTCP["phase"].WithLabelValues(r.Target, r.Family, "dns").Set(r.Phases.DNS.Seconds())
+		d.Metrics.MetricsTCP["phase"].WithLabelValues(r.Target, r.Family, "connect").Set(r.Phases.Connect.Seconds())
+		d.Metrics.MetricsTCP["phase"].WithLabelValues(r.Target, r.Family, "total").Set(r.Phases.Total.Seconds())
+	}
+}
+
+// UpdateFamilyMetrics sets the per address family gauges from a dual stack
+// comparison
+func (d *Daemon) UpdateFamilyMetrics(reports []*models.FamilyReport) {
+	for _, r := range reports {
+		if r.Broken {
+			d.Logger.Errorw("Address family broken", "family", r.Family, "reason", r.Reason)
+		}
+
+		up, broken := 0.0, 0.0
+		if r.Reachable {
+			up = 1
+		}
+		if r.Broken {
+			broken = 1
+		}
+
+		d.Metrics.MetricsFamily["up"].WithLabelValues(r.Family).Set(up)
+		d.Metrics.MetricsFamily["broken"].WithLabelValues(r.Family).Set(broken)
+		d.Metrics.MetricsFamily["loss_ratio"].WithLabelValues(r.Family).Set(r.Loss)
+		d.Metrics.MetricsFamily["latency"].WithLabelValues(r.Family).Set(r.Latency.Seconds())
+		d.Metrics.MetricsFamily["connect"].WithLabelValues(r.Family).Set(r.Connect.Seconds())
+		d.Metrics.MetricsFamily["dns_failure_ratio"].WithLabelValues(r.Family).Set(r.DNSFailureRate)
+	}
+}
+
+// UpdateWANMetrics sets the per WAN gauges from a set of bound probe reports
+func (d *Daemon) UpdateWANMetrics(reports []*models.WANReport) {
+	for _, r := range reports {
+		if r.Error != "" {
+			d.Logger.Errorw("Errored probing WAN", "wan", r.Name, "source", r.Source, "error", r.Error)
+		}
+
+		up := 0.0
+		if r.Up {
+			up = 1
+		}
+		d.Metrics.MetricsWAN["up"].WithLabelValues(r.Name).Set(up)
+		d.Metrics.MetricsWAN["loss_ratio"].WithLabelValues(r.Name).Set(r.Loss)
+		d.Metrics.MetricsWAN["latency"].WithLabelValues(r.Name).Set(r.Latency.Seconds())
+		d.Metrics.MetricsWAN["jitter"].WithLabelValues(r.Name).Set(r.Jitter.Seconds())
+		d.Metrics.MetricsWAN["connect"].WithLabelValues(r.Name).Set(r.Connect.Seconds())
+
+		for _, p := range r.Pings {
+			if p.NoRecord || p.Body == nil {
 				continue
 			}
-			if ret.Body == nil {
-				d.Logger.Errorw("received empty body without error")
+			d.Metrics.MetricsWAN["target_loss"].WithLabelValues(r.Name, p.Hostname).Set(p.Body.PacketLoss / 100)
+			d.Metrics.MetricsWAN["target_rtt"].WithLabelValues(r.Name, p.Hostname).Set(p.Body.AvgResponseTime.Seconds())
+		}
+		for _, t := range r.TCP {
+			if t.NoRecord {
 				continue
 			}
-			d.Logger.Info("Received fastmile data, updating metrics")
+			tcpUp := 0.0
+			if t.Up {
+				tcpUp = 1
+			}
+			d.Metrics.MetricsWAN["target_tcp"].WithLabelValues(r.Name, t.Target).Set(tcpUp)
+		}
+	}
+}
+
+// UpdateHTTPMetrics sets the per url HTTP gauges from a set of reports
+func (d *Daemon) UpdateHTTPMetrics(reports []*models.HTTPReport) {
+	for _, r := range reports {
+		up := 1.0
+		if !r.Up {
+			up = 0
+			d.Logger.Errorw("HTTP check failed", "url", r.URL, "status_code", r.StatusCode, "error", r.Error)
+		}
+
+		d.Metrics.MetricsHTTP["up"].WithLabelValues(r.URL).Set(up)
+		d.Metrics.MetricsHTTP["status_code"].WithLabelValues(r.URL).Set(float64(r.StatusCode))
+		d.Metrics.MetricsHTTP["phase"].WithLabelValues(r.URL, "dns").Set(r.Phases.DNS.Seconds())
+		d.Metrics.MetricsHTTP["phase"].WithLabelValues(r.URL, "connect").Set(r.Phases.Connect.Seconds())
+		d.Metrics.MetricsHTTP["phase"].WithLabelValues(r.URL, "tls").Set(r.Phases.TLS.Seconds())
+		d.Metrics.MetricsHTTP["phase"].WithLabelValues(r.URL, "ttfb").Set(r.Phases.TTFB.Seconds())
+		d.Metrics.MetricsHTTP["phase"].WithLabelValues(r.URL, "total").Set(r.Phases.Total.Seconds())
+	}
+}
+
+// startLoadTest starts a pending speed test, or else a pending bufferbloat
+// test, unless status or discovery checks or another load test are running. It is called
+// again as each of those finishes
+func (d *Daemon) startLoadTest(ctx context.Context, wg *sync.WaitGroup) {
+	if d.checking || d.discovering || d.loadTesting {
+		return
+	}
+
+	switch {
+	case d.speedTestPending:
+		d.speedTestPending = false
+		d.Logger.Info("Running speed test...")
+		d.loadTesting = true
+		wg.Add(1)
+		go d.SpeedTestAsync(ctx, wg)
+	case d.bufferbloatPending:
+		d.bufferbloatPending = false
+		d.Logger.Info("Running bufferbloat test...")
+		d.loadTesting = true
+		wg.Add(1)
+		go d.BufferbloatAsync(ctx, wg)
+	}
+}
+
+// SpeedTestAsync is for running in a goroutine, runs a speed test and returns
+// the report on SpeedTestReturnChannel
+func (d *Daemon) SpeedTestAsync(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	d.SpeedTestReturnChannel <- d.SpeedTest.Run(ctx)
+}
+
+// UpdateSpeedTestMetrics sets the speed test gauges from a report, clearing
+// the band and PCI labeled series of previous runs
+func (d *Daemon) UpdateSpeedTestMetrics(report *models.SpeedTestReport) {
+	if report.RadioError != "" {
+		d.Logger.Errorw("Failed to tag speed test with radio status", "error", report.RadioError)
+	}
 
-			metrics.Metrics5G["cell_id"].Set(ret.Stat5G().ID())
-			metrics.Metrics5G["band"].Set(ret.Stat5G().Band64())
-			metrics.Metrics5G["snr"].Set(ret.Stat5G().SNRCurrent)
-			metrics.Metrics5G["rsrp"].Set(ret.Stat5G().RSRPCurrent)
-			metrics.Metrics5G["rsrq"].Set(ret.Stat5G().RSRQCurrent)
-			metrics.Metrics5G["arfcn"].Set(ret.Stat5G().DownlinkNRARFCN)
+	radio := report.Radio5G
+	if radio.Band == "" {
+		radio = report.RadioLTE
+	}
 
-			metrics.MetricsLTE["cell_id"].Set(ret.StatLTE().ID())
-			metrics.MetricsLTE["band"].Set(ret.StatLTE().Band64())
-			metrics.MetricsLTE["snr"].Set(ret.StatLTE().SNRCurrent)
-			metrics.MetricsLTE["rsrp"].Set(ret.StatLTE().RSRPCurrent)
-			metrics.MetricsLTE["rsrq"].Set(ret.StatLTE().RSRQCurrent)
-			metrics.MetricsLTE["arfcn"].Set(ret.StatLTE().DownlinkEarfcn)
+	d.Metrics.MetricsSpeedTest["throughput"].Reset()
+	d.Metrics.MetricsSpeedTest["radio_snr"].Reset()
 
-			metrics.MetricsMisc["connection_status"].Set(ret.Status())
-			metrics.MetricsMisc["bytes_sent"].Set(ret.BytesSent())
-			metrics.MetricsMisc["bytes_recv"].Set(ret.BytesRecv())
+	if report.Radio5G.Band != "" {
+		d.Metrics.MetricsSpeedTest["radio_snr"].WithLabelValues("5g", report.Radio5G.Band, report.Radio5G.PCI).Set(report.Radio5G.SNR)
+	}
+	if report.RadioLTE.Band != "" {
+		d.Metrics.MetricsSpeedTest["radio_snr"].WithLabelValues("lte", report.RadioLTE.Band, report.RadioLTE.PCI).Set(report.RadioLTE.SNR)
+	}
 
+	d.Metrics.MetricsSpeedTest["latency"].WithLabelValues("idle", "avg").Set(report.IdleLatency.Avg.Seconds())
+	d.Metrics.MetricsSpeedTest["latency"].WithLabelValues("idle", "max").Set(report.IdleLatency.Max.Seconds())
+
+	for _, t := range []*models.ThroughputReport{report.Download, report.Upload} {
+		if t == nil {
+			continue
+		}
+		if t.Error != "" {
+			d.Logger.Errorw("Speed test stream errored", "direction", t.Direction, "error", t.Error)
 		}
+
+		d.Metrics.MetricsSpeedTest["throughput"].WithLabelValues(t.Direction, radio.Band, radio.PCI).Set(t.BitsPerSecond)
+		d.Metrics.MetricsSpeedTest["stream_stddev"].WithLabelValues(t.Direction).Set(t.StreamStdDev)
+		d.Metrics.MetricsSpeedTest["latency"].WithLabelValues(t.Direction, "avg").Set(t.Latency.Avg.Seconds())
+		d.Metrics.MetricsSpeedTest["latency"].WithLabelValues(t.Direction, "max").Set(t.Latency.Max.Seconds())
+		d.Metrics.MetricsSpeedTest["last_run"].WithLabelValues(t.Direction).Set(float64(time.Now().Unix()))
 	}
+}
 
+// BufferbloatAsync is for running in a goroutine, runs a bufferbloat test and
+// returns the report on BufferbloatReturnChannel
+func (d *Daemon) BufferbloatAsync(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	d.BufferbloatReturnChannel <- d.SpeedTest.Bufferbloat(ctx, d.Status)
+}
+
+// UpdateBufferbloatMetrics sets the bufferbloat gauges from a report, clearing
+// the grade series of previous runs
+func (d *Daemon) UpdateBufferbloatMetrics(report *models.BufferbloatReport) {
+	if report.Error != "" {
+		d.Logger.Errorw("Bufferbloat test failed", "error", report.Error)
+		return
+	}
+
+	d.Metrics.MetricsBufferbloat["grade"].Reset()
+	d.Metrics.MetricsBufferbloat["grade"].WithLabelValues("overall", report.Grade).Set(1)
+	d.Metrics.MetricsBufferbloat["latency"].WithLabelValues("idle").Set(report.Idle.Avg.Seconds())
+
+	for _, loaded := range []*models.LoadedLatencyReport{report.Download, report.Upload} {
+		if loaded == nil {
+			continue
+		}
+		if loaded.Throughput != nil && loaded.Throughput.Error != "" {
+			d.Logger.Errorw("Bufferbloat load errored", "direction", loaded.Direction, "error", loaded.Throughput.Error)
+		}
+		d.Metrics.MetricsBufferbloat["latency"].WithLabelValues(loaded.Direction).Set(loaded.Latency.Avg.Seconds())
+		d.Metrics.MetricsBufferbloat["increase"].WithLabelValues(loaded.Direction).Set(loaded.Increase.Seconds())
+		d.Metrics.MetricsBufferbloat["grade"].WithLabelValues(loaded.Direction, loaded.Grade).Set(1)
+	}
+}
+
+func throughputOf(t *models.ThroughputReport) float64 {
+	if t == nil {
+		return 0
+	}
+	return t.BitsPerSecond
 }
 
 func (d *Daemon) BackgroundHTTPServer() {
@@ -0,0 +1,228 @@
+package clients
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/metrics"
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// radioScrape returns a trashcan scrape attached to a 5G cell and an LTE
+// anchor, and the time it took to answer
+func radioScrape(band string, pci string, snr float64, took time.Duration) *models.FastmileReturn {
+	return &models.FastmileReturn{
+		Duration: took,
+		Body: &models.FastmileRadioStatus{
+			Cell5GStats:  []*models.Cell5GStats{{Stat: &models.Cell5GStat{Band: band, PhysicalCellID: pci, SNRCurrent: snr}}},
+			CellLTEStats: []*models.CellLTEStats{{Stat: &models.CellLTEStat{Band: "B66", PhysicalCellID: "121"}}},
+		},
+	}
+}
+
+func TestDaemonRegistry(t *testing.T) {
+	scrape := func(d *Daemon, path string) string {
+		d.RegisterMetrics()
+		d.RegisterHandlers()
+		srv := httptest.NewServer(d.Mux)
+		defer srv.Close()
+
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	// two daemons in one process each get their own registry, mux and metrics
+	a, err := NewDaemon("http://127.0.0.1:1", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.MetricsPath = "/prom"
+	a.RuntimeMetrics = true
+	a.LegacyMetricNames = true
+
+	b, err := NewDaemon("http://127.0.0.1:1", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.MetricGroups = []string{metrics.GroupLTE}
+
+	a.UpdateRadioMetrics(radioScrape("n41", "392", 9, 0))
+	lte := radioScrape("n71", "120", 14, 0)
+	lte.Body.CellLTEStats[0].Stat.SNRCurrent = 4
+	b.UpdateRadioMetrics(lte)
+
+	body := scrape(a, "/prom")
+	if !strings.Contains(body, "gomo_5g_snr_db 9") || !strings.Contains(body, "go_goroutines") {
+		t.Fatalf("Expected radio and runtime metrics but got:\n%s", body)
+	}
+	if !strings.Contains(body, "# TYPE gomo_5g_snr gauge") {
+		t.Fatalf("Expected the legacy radio metric names but got:\n%s", body)
+	}
+	if !strings.Contains(body, "gomo_lte_snr_db 0") {
+		t.Fatalf("Expected LTE SNR 0 from the first daemon's scrape but got:\n%s", body)
+	}
+
+	body = scrape(b, DefaultMetricsPath)
+	if !strings.Contains(body, "gomo_lte_snr_db") || strings.Contains(body, "gomo_5g_snr") || strings.Contains(body, "gomo_lte_snr ") || strings.Contains(body, "go_goroutines") {
+		t.Fatalf("Expected only LTE metrics but got:\n%s", body)
+	}
+	if !strings.Contains(body, "gomo_lte_snr_db 4") {
+		t.Fatalf("Expected LTE SNR 4 from the second daemon's scrape but got:\n%s", body)
+	}
+	if v := testutil.ToFloat64(b.Metrics.Metric5GCurrentSNR); v != 14 {
+		t.Fatalf("Expected the second daemon's own 5G SNR of 14 but got %f", v)
+	}
+	if v := testutil.ToFloat64(a.Metrics.Metric5GCurrentSNR); v != 9 {
+		t.Fatalf("Expected the first daemon's 5G SNR to stay 9 but got %f", v)
+	}
+}
+
+func TestHandover(t *testing.T) {
+	d, err := NewDaemon("http://127.0.0.1:1", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.BandLabels = true
+
+	d.UpdateRadioMetrics(radioScrape("n41", "392", 12, 0))
+	d.UpdateRadioMetrics(radioScrape("n71", "120", 12, 0))
+
+	// only the cell handed over to is left
+	if n := testutil.CollectAndCount(d.Metrics.Metric5GCellInfo); n != 1 {
+		t.Fatalf("Expected 1 cell info series after handover but got %d", n)
+	}
+	if v := testutil.ToFloat64(d.Metrics.Metrics5GByCell["snr"].WithLabelValues("n71", "120")); v != 12 {
+		t.Fatalf("Expected SNR 12 on n71 but got %f", v)
+	}
+	if n := testutil.CollectAndCount(d.Metrics.Metrics5GByCell["snr"]); n != 1 {
+		t.Fatalf("Expected 1 SNR series after handover but got %d", n)
+	}
+
+	// a detached radio has no cell
+	d.UpdateRadioMetrics(radioScrape("", "", 12, 0))
+	if n := testutil.CollectAndCount(d.Metrics.Metric5GCellInfo); n != 0 {
+		t.Fatalf("Expected no cell info while detached but got %d", n)
+	}
+
+	// the signal sampled on n71 stays in its histogram, the detached scrape isn't sampled
+	m := &dto.Metric{}
+	if err := d.Metrics.Metric5GSNRHistogram.WithLabelValues("n71").(prometheus.Histogram).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Histogram.GetSampleCount() != 1 || m.Histogram.GetSampleSum() != 12 {
+		t.Fatalf("Expected one SNR sample of 12 on n71 but got %d summing to %f", m.Histogram.GetSampleCount(), m.Histogram.GetSampleSum())
+	}
+}
+
+func TestStaleRadioMetrics(t *testing.T) {
+	d, err := NewDaemon("http://127.0.0.1:1", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.StaleAfter = 2
+
+	ok := radioScrape("n41", "392", 12, 0)
+	failed := &models.FastmileReturn{Error: errors.New("timeout")}
+
+	d.UpdateScrapeMetrics(ok)
+	d.UpdateRadioMetrics(ok)
+
+	// the first failure keeps the last values
+	d.UpdateScrapeMetrics(failed)
+	if v := testutil.ToFloat64(d.Metrics.Metric5GCurrentSNR); v != 12 {
+		t.Fatalf("Expected SNR 12 after one failure but got %f", v)
+	}
+	if v := testutil.ToFloat64(d.Metrics.MetricScrapeUp); v != 0 {
+		t.Fatalf("Expected scrape down but got %f", v)
+	}
+
+	d.UpdateScrapeMetrics(failed)
+	if v := testutil.ToFloat64(d.Metrics.Metric5GCurrentSNR); !math.IsNaN(v) {
+		t.Fatalf("Expected SNR cleared after two failures but got %f", v)
+	}
+	if n := testutil.CollectAndCount(d.Metrics.Metric5GCellInfo); n != 0 {
+		t.Fatalf("Expected no cell info once stale but got %d", n)
+	}
+
+	d.UpdateScrapeMetrics(ok)
+	if v := testutil.ToFloat64(d.Metrics.MetricScrapeConsecutiveFailures); v != 0 {
+		t.Fatalf("Expected failures reset after a success but got %f", v)
+	}
+}
+
+func TestRadioMetrics(t *testing.T) {
+	d, err := NewDaemon("http://127.0.0.1:1", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scrape := func(apns ...string) *models.FastmileReturn {
+		body := &models.FastmileRadioStatus{
+			EthernetStats: []*models.EthernetStats{{Enable: 1, Status: "Up", Stat: &models.EthernetStatsStat{BytesSent: 2048}}},
+		}
+		for _, apn := range apns {
+			body.ApCfg = append(body.ApCfg, &models.ApnCfg{APN: apn, ServiceType: "Internet", Enable: 1, ConnectionState: 1})
+		}
+		return &models.FastmileReturn{Body: body}
+	}
+
+	// a scrape without any radio sections only sets what it carries
+	d.UpdateRadioMetrics(scrape("fbb.home", "ims"))
+	if v := testutil.ToFloat64(d.Metrics.MetricEthernetUp); v != 1 {
+		t.Fatalf("Expected ethernet up but got %f", v)
+	}
+	if v := testutil.ToFloat64(d.Metrics.MetricEthernetBytesSent); v != 2048 {
+		t.Fatalf("Expected 2048 ethernet bytes sent but got %f", v)
+	}
+	if v := testutil.ToFloat64(d.Metrics.MetricsAPN["connection_state"].WithLabelValues("ims", "Internet")); v != 1 {
+		t.Fatalf("Expected the ims APN connected but got %f", v)
+	}
+
+	// an APN no longer configured is removed
+	d.UpdateRadioMetrics(scrape("fbb.home"))
+	if n := testutil.CollectAndCount(d.Metrics.MetricsAPN["enabled"]); n != 1 {
+		t.Fatalf("Expected 1 APN series but got %d", n)
+	}
+}
+
+func TestPingCounters(t *testing.T) {
+	d, err := NewDaemon("http://127.0.0.1:1", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a check that failed outright still counts what it would have sent as lost
+	failed := &models.PingReportReturn{
+		Hostname: "counters.example",
+		Error:    errors.New("no such host"),
+		Body:     &models.PingReport{Hostname: "counters.example", PacketsSent: 5, PacketLoss: 100},
+	}
+	ok := &models.PingReportReturn{
+		Hostname: "counters.example",
+		Body:     &models.PingReport{Hostname: "counters.example", PacketsSent: 5, PacketsRecv: 5},
+	}
+	d.UpdatePingMetrics([]*models.PingReportReturn{failed, ok})
+
+	if v := testutil.ToFloat64(d.Metrics.MetricsPingCounters["packets_sent"].WithLabelValues("counters.example", "")); v != 10 {
+		t.Fatalf("Expected 10 packets sent but got %f", v)
+	}
+	if v := testutil.ToFloat64(d.Metrics.MetricsPingCounters["packets_lost"].WithLabelValues("counters.example", "")); v != 5 {
+		t.Fatalf("Expected the failed check's 5 packets lost but got %f", v)
+	}
+}
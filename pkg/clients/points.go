@@ -0,0 +1,159 @@
+package clients
+
+import (
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/push"
+)
+
+// writePoints writes points to the push sink when it takes samples as they
+// are taken, ex. InfluxDB
+func (d *Daemon) writePoints(points []push.Point) {
+	if w, ok := d.Push.(push.PointWriter); ok && len(points) > 0 {
+		w.Write(points...)
+	}
+}
+
+// attachedCell returns the band and PCI of the attached 5G cell, or of the LTE
+// cell when 5G isn't attached, the same cell speed tests are tagged with
+func attachedCell(ret *models.FastmileReturn) models.RadioTag {
+	body := ret.Body
+	if len(body.Cell5GStats) > 0 && body.Cell5GStats[0] != nil && body.Cell5GStats[0].Stat != nil {
+		if nr := ret.Stat5G(); nr.PhysicalCellID != "" {
+			return models.RadioTag{Band: nr.Band, PCI: nr.PhysicalCellID, SNR: nr.SNRCurrent, RSRP: nr.RSRPCurrent}
+		}
+	}
+	if len(body.CellLTEStats) > 0 && body.CellLTEStats[0] != nil && body.CellLTEStats[0].Stat != nil {
+		if lte := ret.StatLTE(); lte.PhysicalCellID != "" {
+			return models.RadioTag{Band: lte.Band, PCI: lte.PhysicalCellID, SNR: lte.SNRCurrent, RSRP: lte.RSRPCurrent}
+		}
+	}
+	return models.RadioTag{}
+}
+
+// radioPoints returns a point per attached radio of a trashcan scrape and one
+// of the cellular traffic totals, taken when the trashcan was asked
+func radioPoints(ret *models.FastmileReturn, cell models.RadioTag) []push.Point {
+	var points []push.Point
+	body := ret.Body
+
+	if len(body.Cell5GStats) > 0 && body.Cell5GStats[0] != nil && body.Cell5GStats[0].Stat != nil {
+		if nr := ret.Stat5G(); nr.PhysicalCellID != "" {
+			points = append(points, push.Point{
+				Measurement: "gomo_radio",
+				Tags:        map[string]string{"radio": "5g", "band": nr.Band, "pci": nr.PhysicalCellID},
+				Fields: map[string]float64{
+					"snr_db":                nr.SNRCurrent,
+					"rsrp_dbm":              nr.RSRPCurrent,
+					"rsrq_db":               nr.RSRQCurrent,
+					"arfcn":                 nr.DownlinkNRARFCN,
+					"rsrp_strength_index":   nr.RSRPStrengthIndexCurrent,
+					"signal_strength_level": nr.SignalStrengthLevel,
+				},
+				Time: ret.Start,
+			})
+		}
+	}
+
+	if len(body.CellLTEStats) > 0 && body.CellLTEStats[0] != nil && body.CellLTEStats[0].Stat != nil {
+		if lte := ret.StatLTE(); lte.PhysicalCellID != "" {
+			points = append(points, push.Point{
+				Measurement: "gomo_radio",
+				Tags:        map[string]string{"radio": "lte", "band": lte.Band, "pci": lte.PhysicalCellID},
+				Fields: map[string]float64{
+					"snr_db":                lte.SNRCurrent,
+					"rsrp_dbm":              lte.RSRPCurrent,
+					"rsrq_db":               lte.RSRQCurrent,
+					"rssi_dbm":              lte.RSSICurrent,
+					"arfcn":                 lte.DownlinkEarfcn,
+					"rsrp_strength_index":   lte.RSRPStrengthIndexCurrent,
+					"signal_strength_level": lte.SignalStrengthLevel,
+				},
+				Time: ret.Start,
+			})
+		}
+	}
+
+	if len(body.CellularStats) > 0 && body.CellularStats[0] != nil {
+		fields := map[string]float64{
+			"sent_bytes":     ret.BytesSent(),
+			"received_bytes": ret.BytesRecv(),
+		}
+		if len(body.ConnectionStatus) > 0 && body.ConnectionStatus[0] != nil {
+			fields["connection_status"] = ret.Status()
+		}
+		points = append(points, push.Point{
+			Measurement: "gomo_cellular",
+			Tags:        map[string]string{"band": cell.Band, "pci": cell.PCI},
+			Fields:      fields,
+			Time:        ret.Start,
+		})
+	}
+
+	return points
+}
+
+// pingPoints returns a point per ping target of a status report, tagged with
+// the cell attached at the time
+func pingPoints(reports []*models.PingReportReturn, at time.Time, cell models.RadioTag) []push.Point {
+	points := make([]push.Point, 0, len(reports))
+	for _, r := range reports {
+		if r.NoRecord {
+			continue
+		}
+
+		fields := map[string]float64{"up": 0}
+		if r.Body != nil {
+			fields["loss_ratio"] = r.Body.PacketLoss / 100
+			fields["loss_streak"] = float64(r.Body.LossStreak)
+			fields["packets_sent"] = float64(r.Body.PacketsSent)
+			fields["packets_received"] = float64(r.Body.PacketsRecv)
+		}
+		if r.Error == nil && r.Body != nil {
+			fields["up"] = 1
+			fields["rtt_min_seconds"] = r.Body.MinResponseTime.Seconds()
+			fields["rtt_avg_seconds"] = r.Body.AvgResponseTime.Seconds()
+			fields["rtt_max_seconds"] = r.Body.MaxResponseTime.Seconds()
+			fields["jitter_seconds"] = r.Body.Jitter.Seconds()
+		}
+
+		points = append(points, push.Point{
+			Measurement: "gomo_ping",
+			Tags:        map[string]string{"target": r.Hostname, "family": r.Family, "band": cell.Band, "pci": cell.PCI},
+			Fields:      fields,
+			Time:        at,
+		})
+	}
+	return points
+}
+
+// speedTestPoints returns a point per direction of a speed test, tagged with
+// the cell it ran on
+func speedTestPoints(report *models.SpeedTestReport) []push.Point {
+	radio := report.Radio5G
+	if radio.Band == "" {
+		radio = report.RadioLTE
+	}
+
+	var points []push.Point
+	for _, t := range []*models.ThroughputReport{report.Download, report.Upload} {
+		if t == nil {
+			continue
+		}
+		points = append(points, push.Point{
+			Measurement: "gomo_speedtest",
+			Tags:        map[string]string{"direction": t.Direction, "band": radio.Band, "pci": radio.PCI},
+			Fields: map[string]float64{
+				"bits_per_second":     t.BitsPerSecond,
+				"bytes":               float64(t.Bytes),
+				"duration_seconds":    t.Duration.Seconds(),
+				"stream_stddev":       t.StreamStdDev,
+				"latency_avg_seconds": t.Latency.Avg.Seconds(),
+				"latency_max_seconds": t.Latency.Max.Seconds(),
+			},
+			Time: report.Start,
+		})
+	}
+	return points
+}
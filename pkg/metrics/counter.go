@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ReportedCounter is a counter whose total is kept by the trashcan rather
+// than counted by gomo, ex. the bytes sent over the cellular connection. It is
+// set to each reported total and resets whenever the trashcan restarts, which
+// rate() and increase() handle like any other counter reset. Nothing is
+// exported until the first total is set or while the total is NaN
+type ReportedCounter struct {
+	desc *prometheus.Desc
+
+	mu    sync.Mutex
+	value float64
+}
+
+// NewReportedCounter returns a ReportedCounter with no total yet
+func NewReportedCounter(opts prometheus.CounterOpts) *ReportedCounter {
+	return &ReportedCounter{
+		desc:  prometheus.NewDesc(prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), opts.Help, nil, opts.ConstLabels),
+		value: math.NaN(),
+	}
+}
+
+// Set sets the counter to the total reported by the trashcan
+func (c *ReportedCounter) Set(value float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = value
+}
+
+// Describe implements prometheus.Collector
+func (c *ReportedCounter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *ReportedCounter) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	value := c.value
+	c.mu.Unlock()
+
+	if math.IsNaN(value) {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, value)
+}
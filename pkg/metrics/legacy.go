@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+	Legacy Prometheus Metrics
+
+	The trashcan metrics as they were named before following the prometheus
+	naming conventions, exported alongside the current names when legacy
+	metric names are enabled so existing dashboards keep working
+*/
+
+func legacyGauge(subsystem string, name string, current string) prometheus.Gauge {
+	return prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      "Deprecated, use " + current + " instead",
+	})
+}
+
+// legacyRadioMetrics returns new legacy metrics mapped from a trashcan scrape,
+// band is in GHz as it used to be
+func legacyRadioMetrics() []RadioMetric {
+	return []RadioMetric{
+		{Group5G, legacyGauge("5g", "cell_id", "gomo_5g_physical_cell_id"), nr(func(c *models.Cell5GStat) float64 { return c.ID() })},
+		{Group5G, legacyGauge("5g", "band", "gomo_5g_band_frequency_hertz"), nr(func(c *models.Cell5GStat) float64 { return c.Band64() })},
+		{Group5G, legacyGauge("5g", "snr", "gomo_5g_snr_db"), nr(func(c *models.Cell5GStat) float64 { return c.SNRCurrent })},
+		{Group5G, legacyGauge("5g", "rsrp", "gomo_5g_rsrp_dbm"), nr(func(c *models.Cell5GStat) float64 { return c.RSRPCurrent })},
+		{Group5G, legacyGauge("5g", "rsrq", "gomo_5g_rsrq_db"), nr(func(c *models.Cell5GStat) float64 { return c.RSRQCurrent })},
+
+		{GroupLTE, legacyGauge("lte", "cell_id", "gomo_lte_physical_cell_id"), lte(func(c *models.CellLTEStat) float64 { return c.ID() })},
+		{GroupLTE, legacyGauge("lte", "band", "gomo_lte_band_frequency_hertz"), lte(func(c *models.CellLTEStat) float64 { return c.Band64() })},
+		{GroupLTE, legacyGauge("lte", "snr", "gomo_lte_snr_db"), lte(func(c *models.CellLTEStat) float64 { return c.SNRCurrent })},
+		{GroupLTE, legacyGauge("lte", "rsrp", "gomo_lte_rsrp_dbm"), lte(func(c *models.CellLTEStat) float64 { return c.RSRPCurrent })},
+		{GroupLTE, legacyGauge("lte", "rsrq", "gomo_lte_rsrq_db"), lte(func(c *models.CellLTEStat) float64 { return c.RSRQCurrent })},
+		{GroupLTE, legacyGauge("lte", "rssi", "gomo_lte_rssi_dbm"), lte(func(c *models.CellLTEStat) float64 { return c.RSSICurrent })},
+		{GroupLTE, legacyGauge("lte", "downlink_nr_arfcn", "gomo_lte_downlink_earfcn"), lte(func(c *models.CellLTEStat) float64 { return c.DownlinkEarfcn })},
+
+		{GroupMisc, legacyGauge("cell", "bytes_sent", "gomo_cell_sent_bytes_total"), cellular(func(c *models.CellularStats) float64 { return float64(c.BytesSent) })},
+		{GroupMisc, legacyGauge("cell", "bytes_received", "gomo_cell_received_bytes_total"), cellular(func(c *models.CellularStats) float64 { return float64(c.BytesReceived) })},
+	}
+}
@@ -2,154 +2,1393 @@ package metrics
 
 import "github.com/prometheus/client_golang/prometheus"
 
-/*
-	5G Prometheus Metrics
-*/
-
-var Metric5GCurrentCellID = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "5g",
-	Name:      "cell_id",
-	Help:      "The current CellID of the 5G radio. GHz",
-})
-
-var Metric5GCurrentBand = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "5g",
-	Name:      "band",
-	Help:      "The current Band of the 5G radio. GHz",
-})
-
-var Metric5GCurrentSNR = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "5g",
-	Name:      "snr",
-	Help:      "The current SNR of the 5G radio at this point in time. dB",
-})
-
-var Metric5GCurrentRSRP = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "5g",
-	Name:      "rsrp",
-	Help:      "The current RSRP of the 5G radio at this point in time. dBm",
-})
-
-var Metric5GCurrentRSRQ = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "5g",
-	Name:      "rsrq",
-	Help:      "The current RSRQ of the 5G radio at this point in time. dBm",
-})
-
-var Metric5GCurrentDownlinkARFCN = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "5g",
-	Name:      "downlink_nr_arfcn",
-	Help:      "The absolute radio frequency channel number of teh radio at this point in time",
-})
-
-// Metrics5G is a convenience var for 5G metric gauges
-var Metrics5G = map[string]prometheus.Gauge{
-	"cell_id": Metric5GCurrentCellID,
-	"band":    Metric5GCurrentBand,
-	"snr":     Metric5GCurrentSNR,
-	"rsrp":    Metric5GCurrentRSRP,
-	"rsrq":    Metric5GCurrentRSRQ,
-	"arfcn":   Metric5GCurrentDownlinkARFCN,
+// Metric groups which can be enabled or disabled as a whole
+const (
+	Group5G          = "5g"
+	GroupLTE         = "lte"
+	GroupMisc        = "misc"
+	GroupScrape      = "scrape"
+	GroupSignal      = "signal"
+	GroupEthernet    = "ethernet"
+	GroupCA          = "ca"
+	GroupAPN         = "apn"
+	GroupPing        = "ping"
+	GroupDNS         = "dns"
+	GroupTCP         = "tcp"
+	GroupHTTP        = "http"
+	GroupLink        = "link"
+	GroupMTU         = "mtu"
+	GroupCGNAT       = "cgnat"
+	GroupNAT         = "nat"
+	GroupFamily      = "family"
+	GroupWAN         = "wan"
+	GroupOutage      = "outage"
+	GroupSLO         = "slo"
+	GroupSpeedTest   = "speedtest"
+	GroupBufferbloat = "bufferbloat"
+	GroupPush        = "push"
+)
+
+// Groups lists every metric group
+var Groups = []string{
+	Group5G, GroupLTE, GroupMisc, GroupScrape, GroupSignal, GroupEthernet, GroupCA, GroupAPN,
+	GroupPing, GroupDNS, GroupTCP, GroupHTTP, GroupLink, GroupMTU, GroupCGNAT, GroupNAT, GroupFamily, GroupWAN,
+	GroupOutage, GroupSLO, GroupSpeedTest, GroupBufferbloat, GroupPush,
+}
+
+// RSRPBuckets are histogram buckets in dBm from the edge of coverage up to
+// right next to the tower, in 5 dB steps so the usual -105 dBm and -115 dBm
+// thresholds are bucket boundaries
+var RSRPBuckets = []float64{-130, -125, -120, -115, -110, -105, -100, -95, -90, -85, -80, -75, -70}
+
+// RSRQBuckets are histogram buckets in dB over the -20 to -3 dB range both
+// radios report RSRQ in, finer where quality is usually fair to poor
+var RSRQBuckets = []float64{-20, -18, -16, -15, -14, -13, -12, -11, -10, -9, -8, -6, -4}
+
+// LTESNRBuckets are histogram buckets in dB over the -20 to 30 dB range LTE
+// reports SNR in
+var LTESNRBuckets = []float64{-10, -5, -2.5, 0, 2.5, 5, 7.5, 10, 12.5, 15, 17.5, 20, 25, 30}
+
+// NRSNRBuckets are histogram buckets in dB over the -23 to 40 dB range 5G
+// reports SINR in, which reaches higher than LTE on clean mid-band carriers
+var NRSNRBuckets = []float64{-10, -5, -2.5, 0, 2.5, 5, 7.5, 10, 12.5, 15, 17.5, 20, 25, 30, 35, 40}
+
+func signalHistogram(subsystem string, name string, help string, buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gomo",
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}, []string{"band"})
 }
 
-/*
-	LTE Prometheus Metrics
-*/
-
-var MetricLTECurrentCellID = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "lte",
-	Name:      "cell_id",
-	Help:      "The current CellID of the 5G radio. GHz",
-})
-
-var MetricLTECurrentBand = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "lte",
-	Name:      "band",
-	Help:      "The current Band of the 5G radio. GHz",
-})
-
-var MetricLTECurrentSNR = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "lte",
-	Name:      "snr",
-	Help:      "The current SNR of the 5G radio at this point in time. dB",
-})
-
-var MetricLTECurrentRSRP = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "lte",
-	Name:      "rsrp",
-	Help:      "The current RSRP of the 5G radio at this point in time. dBm",
-})
-
-var MetricLTECurrentRSRQ = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "lte",
-	Name:      "rsrq",
-	Help:      "The current RSRQ of the 5G radio at this point in time. dBm",
-})
-
-var MetricLTECurrentDownlinkARFCN = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "lte",
-	Name:      "downlink_nr_arfcn",
-	Help:      "The absolute radio frequency channel number of teh radio at this point in time",
-})
-
-var MetricLTECurrentRSSI = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "lte",
-	Name:      "rssi",
-	Help:      "The absolute radio frequency channel number of teh radio at this point in time",
-})
-
-// MetricsLTE is a convenience var for LTE metric gauges
-var MetricsLTE = map[string]prometheus.Gauge{
-	"cell_id": MetricLTECurrentCellID,
-	"band":    MetricLTECurrentBand,
-	"snr":     MetricLTECurrentSNR,
-	"rsrp":    MetricLTECurrentRSRP,
-	"rsrq":    MetricLTECurrentRSRQ,
-	"rssi":    MetricLTECurrentRSSI,
-	"arfcn":   MetricLTECurrentDownlinkARFCN,
+// ScrapeDurationBuckets are histogram buckets in seconds from a fast local
+// answer up to the default request timeout
+var ScrapeDurationBuckets = []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10, 15}
+
+// PingRTTBuckets are histogram buckets in seconds sized for bursty cellular latency
+var PingRTTBuckets = []float64{.005, .01, .02, .03, .04, .05, .075, .1, .15, .2, .3, .5, .75, 1, 2}
+
+// Set is the metrics of a single daemon. Every collector is created per Set
+// so daemons sharing a process don't share series
+type Set struct {
+	// 5G
+	Metric5GPhysicalCellID      prometheus.Gauge
+	Metric5GBandFrequency       prometheus.Gauge
+	Metric5GCurrentSNR          prometheus.Gauge
+	Metric5GCurrentRSRP         prometheus.Gauge
+	Metric5GCurrentRSRQ         prometheus.Gauge
+	Metric5GDownlinkARFCN       prometheus.Gauge
+	Metric5GRSRPStrengthIndex   prometheus.Gauge
+	Metric5GSignalStrengthLevel prometheus.Gauge
+
+	// Metrics5G is a convenience map of 5G metric gauges
+	Metrics5G map[string]prometheus.Gauge
+
+	Metric5GCellInfo *prometheus.GaugeVec
+
+	// Metrics5GByCell are the 5G signal gauges labeled by band and PCI, exported
+	// in place of the matching Metrics5G gauges when band labels are enabled
+	Metrics5GByCell map[string]*prometheus.GaugeVec
+
+	// LTE
+	MetricLTEPhysicalCellID      prometheus.Gauge
+	MetricLTEBandFrequency       prometheus.Gauge
+	MetricLTECurrentSNR          prometheus.Gauge
+	MetricLTECurrentRSRP         prometheus.Gauge
+	MetricLTECurrentRSRQ         prometheus.Gauge
+	MetricLTEDownlinkEARFCN      prometheus.Gauge
+	MetricLTECurrentRSSI         prometheus.Gauge
+	MetricLTERSRPStrengthIndex   prometheus.Gauge
+	MetricLTESignalStrengthLevel prometheus.Gauge
+
+	// MetricsLTE is a convenience map of LTE metric gauges
+	MetricsLTE map[string]prometheus.Gauge
+
+	MetricLTECellInfo *prometheus.GaugeVec
+
+	// MetricsLTEByCell are the LTE signal gauges labeled by band and PCI, exported
+	// in place of the matching MetricsLTE gauges when band labels are enabled
+	MetricsLTEByCell map[string]*prometheus.GaugeVec
+
+	// Signal Distribution
+	Metric5GSNRHistogram   *prometheus.HistogramVec
+	Metric5GRSRPHistogram  *prometheus.HistogramVec
+	Metric5GRSRQHistogram  *prometheus.HistogramVec
+	MetricLTESNRHistogram  *prometheus.HistogramVec
+	MetricLTERSRPHistogram *prometheus.HistogramVec
+	MetricLTERSRQHistogram *prometheus.HistogramVec
+
+	// Scrape
+	MetricScrapeUp                  prometheus.Gauge
+	MetricScrapeLastSuccess         prometheus.Gauge
+	MetricScrapeConsecutiveFailures prometheus.Gauge
+
+	// MetricsScrape is a convenience map of trashcan scrape health gauges
+	MetricsScrape map[string]prometheus.Gauge
+
+	MetricScrapeSuccesses prometheus.Counter
+	MetricScrapeFailures  prometheus.Counter
+
+	// MetricsScrapeCounters is a convenience map of trashcan scrape counters
+	MetricsScrapeCounters map[string]prometheus.Counter
+
+	MetricScrapePollInterval prometheus.Gauge
+	MetricScrapeDuration     prometheus.Histogram
+
+	// Misc
+	MetricConnectionStatus  prometheus.Gauge
+	MetricCellularBytesSent *ReportedCounter
+	MetricCellularBytesRecv *ReportedCounter
+
+	// MetricsMisc is a convenience map of misc metrics
+	MetricsMisc map[string]Setter
+
+	// Ethernet
+	MetricEthernetEnabled     prometheus.Gauge
+	MetricEthernetUp          prometheus.Gauge
+	MetricEthernetBytesSent   *ReportedCounter
+	MetricEthernetBytesRecv   *ReportedCounter
+	MetricEthernetPacketsSent *ReportedCounter
+	MetricEthernetPacketsRecv *ReportedCounter
+
+	// MetricsEthernet is a convenience map of ethernet port metrics
+	MetricsEthernet map[string]Setter
+
+	// Carrier Aggregation
+	MetricCADownlinkCarriers prometheus.Gauge
+	MetricCAUplinkCarriers   prometheus.Gauge
+
+	// MetricsCA is a convenience map of carrier aggregation gauges
+	MetricsCA map[string]prometheus.Gauge
+
+	// APN
+	MetricAPNEnabled         *prometheus.GaugeVec
+	MetricAPNConnectionState *prometheus.GaugeVec
+
+	// MetricsAPN is a convenience map of APN gauges, labeled by APN and service type
+	MetricsAPN map[string]*prometheus.GaugeVec
+
+	// Ping
+	MetricPingLossRatio     *prometheus.GaugeVec
+	MetricPingLossStreak    *prometheus.GaugeVec
+	MetricPingLossStreakMax *prometheus.GaugeVec
+	MetricPingRTTMin        *prometheus.GaugeVec
+	MetricPingRTTAvg        *prometheus.GaugeVec
+	MetricPingRTTMax        *prometheus.GaugeVec
+	MetricPingJitter        *prometheus.GaugeVec
+	MetricPingUp            *prometheus.GaugeVec
+
+	// MetricsPing is a convenience map of ping metric gauges, labeled by target and family
+	MetricsPing map[string]*prometheus.GaugeVec
+
+	MetricPingRTT *prometheus.HistogramVec
+
+	// MetricsPingHistograms is a convenience map of ping histograms, labeled by target and family
+	MetricsPingHistograms map[string]*prometheus.HistogramVec
+
+	MetricPingPacketsSent *prometheus.CounterVec
+	MetricPingPacketsLost *prometheus.CounterVec
+
+	// MetricsPingCounters is a convenience map of ping counters, labeled by target and family
+	MetricsPingCounters map[string]*prometheus.CounterVec
+
+	// DNS
+	MetricDNSLatencyAvg   *prometheus.GaugeVec
+	MetricDNSLatencyMax   *prometheus.GaugeVec
+	MetricDNSFailureRatio *prometheus.GaugeVec
+	MetricDNSMismatch     *prometheus.GaugeVec
+
+	// MetricsDNS is a convenience map of DNS metric gauges, labeled by resolver, name and family
+	MetricsDNS map[string]*prometheus.GaugeVec
+
+	// TCP
+	MetricTCPPhase *prometheus.GaugeVec
+	MetricTCPUp    *prometheus.GaugeVec
+
+	// MetricsTCP is a convenience map of TCP metric gauges, labeled by target and family
+	MetricsTCP map[string]*prometheus.GaugeVec
+
+	// HTTP
+	MetricHTTPPhase      *prometheus.GaugeVec
+	MetricHTTPStatusCode *prometheus.GaugeVec
+	MetricHTTPUp         *prometheus.GaugeVec
+
+	// MetricsHTTP is a convenience map of HTTP metric gauges, labeled by url
+	MetricsHTTP map[string]*prometheus.GaugeVec
+
+	// Speed Test
+	MetricSpeedTestThroughput   *prometheus.GaugeVec
+	MetricSpeedTestStreamStdDev *prometheus.GaugeVec
+	MetricSpeedTestLatency      *prometheus.GaugeVec
+	MetricSpeedTestRadioSNR     *prometheus.GaugeVec
+	MetricSpeedTestLastRun      *prometheus.GaugeVec
+
+	// MetricsSpeedTest is a convenience map of speed test metric gauges
+	MetricsSpeedTest map[string]*prometheus.GaugeVec
+
+	// Bufferbloat
+	MetricBufferbloatLatency  *prometheus.GaugeVec
+	MetricBufferbloatIncrease *prometheus.GaugeVec
+	MetricBufferbloatGrade    *prometheus.GaugeVec
+
+	// MetricsBufferbloat is a convenience map of bufferbloat metric gauges
+	MetricsBufferbloat map[string]*prometheus.GaugeVec
+
+	// Outage
+	MetricOutagesTotal   *prometheus.CounterVec
+	MetricOutageDowntime *prometheus.CounterVec
+
+	// MetricsOutageCounters is a convenience map of outage counters, labeled by classification
+	MetricsOutageCounters map[string]*prometheus.CounterVec
+
+	MetricOutageState           *prometheus.GaugeVec
+	MetricOutageCurrentDuration *prometheus.GaugeVec
+
+	// MetricsOutage is a convenience map of outage gauges
+	MetricsOutage map[string]*prometheus.GaugeVec
+
+	// LAN vs WAN
+	MetricLinkLoss    *prometheus.GaugeVec
+	MetricLinkLatency *prometheus.GaugeVec
+	MetricLinkJitter  *prometheus.GaugeVec
+	MetricLinkVerdict *prometheus.GaugeVec
+
+	// MetricsLink is a convenience map of LAN vs WAN metric gauges
+	MetricsLink map[string]*prometheus.GaugeVec
+
+	// Path MTU and CGNAT
+	MetricMTUPath *prometheus.GaugeVec
+
+	// MetricsMTU is a convenience map of path MTU metric gauges, labeled by target
+	MetricsMTU map[string]*prometheus.GaugeVec
+
+	MetricCGNATDetected    *prometheus.GaugeVec
+	MetricCGNATSharedSpace *prometheus.GaugeVec
+	MetricCGNATTranslated  *prometheus.GaugeVec
+	MetricCGNATInfo        *prometheus.GaugeVec
+
+	// MetricsCGNAT is a convenience map of CGNAT metric gauges
+	MetricsCGNAT map[string]*prometheus.GaugeVec
+
+	// NAT
+	MetricNATInfo          *prometheus.GaugeVec
+	MetricNATPortPreserved *prometheus.GaugeVec
+	MetricSTUNRTT          *prometheus.GaugeVec
+	MetricSTUNUp           *prometheus.GaugeVec
+
+	// MetricsNAT is a convenience map of NAT and STUN metric gauges
+	MetricsNAT map[string]*prometheus.GaugeVec
+
+	// Dual Stack
+	MetricFamilyUp              *prometheus.GaugeVec
+	MetricFamilyBroken          *prometheus.GaugeVec
+	MetricFamilyLossRatio       *prometheus.GaugeVec
+	MetricFamilyLatency         *prometheus.GaugeVec
+	MetricFamilyConnect         *prometheus.GaugeVec
+	MetricFamilyDNSFailureRatio *prometheus.GaugeVec
+
+	// MetricsFamily is a convenience map of dual stack metric gauges, labeled by family
+	MetricsFamily map[string]*prometheus.GaugeVec
+
+	// Multi-WAN
+	MetricWANUp              *prometheus.GaugeVec
+	MetricWANLossRatio       *prometheus.GaugeVec
+	MetricWANLatency         *prometheus.GaugeVec
+	MetricWANJitter          *prometheus.GaugeVec
+	MetricWANConnect         *prometheus.GaugeVec
+	MetricWANTargetLossRatio *prometheus.GaugeVec
+	MetricWANTargetRTT       *prometheus.GaugeVec
+	MetricWANTargetTCPUp     *prometheus.GaugeVec
+
+	// MetricsWAN is a convenience map of multi-WAN metric gauges, labeled by WAN
+	MetricsWAN map[string]*prometheus.GaugeVec
+
+	MetricSLOObjective       *prometheus.GaugeVec
+	MetricSLOCompliance      *prometheus.GaugeVec
+	MetricSLOBudgetRemaining *prometheus.GaugeVec
+	MetricSLOBurnRate        *prometheus.GaugeVec
+	MetricSLOMet             *prometheus.GaugeVec
+	MetricSLOEvents          *prometheus.GaugeVec
+	MetricSLOGoodEvents      *prometheus.GaugeVec
+
+	// MetricsSLO is a convenience map of SLO gauges, labeled by SLO name
+	MetricsSLO map[string]*prometheus.GaugeVec
+
+	// Push
+	MetricPushSent        *ReportedCounter
+	MetricPushFailed      *ReportedCounter
+	MetricPushDropped     *ReportedCounter
+	MetricPushBuffered    prometheus.Gauge
+	MetricPushLastSuccess prometheus.Gauge
+
+	// MetricsPush is a convenience map of push mode metrics
+	MetricsPush map[string]Setter
+
+	// Trashcan scrape tables
+
+	// RadioMetrics is every metric set from a trashcan scrape
+	RadioMetrics []RadioMetric
+
+	// RadioMetricsByCell holds the signal gauges labeled by band and PCI which
+	// replace their RadioMetrics when band labels are enabled
+	RadioMetricsByCell map[Setter]*prometheus.GaugeVec
+
+	// RadioHistograms is every signal distribution observed from a trashcan scrape
+	RadioHistograms []RadioHistogram
+
+	// LegacyRadioMetrics maps a trashcan scrape onto the legacy metrics
+	LegacyRadioMetrics []RadioMetric
 }
 
-/*
-	Misc Prometheus Metrics
-*/
-
-var MetricConnectionStatus = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "wan",
-	Name:      "connection_status",
-	Help:      "The reported connection status of the device. integer bool",
-})
-
-var MetricCellularBytesSent = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "cell",
-	Name:      "bytes_sent",
-	Help:      "The reported number of bytes sent over the cellular connection this uptime",
-})
-
-var MetricCellularBytesRecv = prometheus.NewGauge(prometheus.GaugeOpts{
-	Namespace: "gomo",
-	Subsystem: "cell",
-	Name:      "bytes_received",
-	Help:      "The reported number of bytes received over the cellular connection this uptime",
-})
-
-// MetricsMisc is a convenience var for misc metric gauges
-var MetricsMisc = map[string]prometheus.Gauge{
-	"connection_status": MetricConnectionStatus,
-	"bytes_sent":        MetricCellularBytesSent,
-	"bytes_recv":        MetricCellularBytesRecv,
+// NewSet returns a Set of freshly created metrics
+func NewSet() *Set {
+	s := &Set{}
+
+	// 5G
+	s.Metric5GPhysicalCellID = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "physical_cell_id",
+		Help:      "The physical cell ID (PCI) of the cell the 5G radio is attached to. integer",
+	})
+
+	s.Metric5GBandFrequency = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "band_frequency_hertz",
+		Help:      "The nominal frequency of the band the 5G radio is attached on. hertz",
+	})
+
+	s.Metric5GCurrentSNR = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "snr_db",
+		Help:      "The current SNR of the 5G radio at this point in time. dB",
+	})
+
+	s.Metric5GCurrentRSRP = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "rsrp_dbm",
+		Help:      "The current RSRP of the 5G radio at this point in time. dBm",
+	})
+
+	s.Metric5GCurrentRSRQ = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "rsrq_db",
+		Help:      "The current RSRQ of the 5G radio at this point in time. dB",
+	})
+
+	s.Metric5GDownlinkARFCN = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "downlink_nr_arfcn",
+		Help:      "The downlink NR absolute radio frequency channel number of the 5G radio. integer",
+	})
+
+	s.Metric5GRSRPStrengthIndex = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "rsrp_strength_index",
+		Help:      "The RSRP strength index the trashcan reports for the 5G radio. 0-100",
+	})
+
+	s.Metric5GSignalStrengthLevel = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "signal_strength_level",
+		Help:      "The signal strength in bars the trashcan displays for the 5G radio. integer",
+	})
+
+	s.Metrics5G = map[string]prometheus.Gauge{
+		"pci":   s.Metric5GPhysicalCellID,
+		"band":  s.Metric5GBandFrequency,
+		"snr":   s.Metric5GCurrentSNR,
+		"rsrp":  s.Metric5GCurrentRSRP,
+		"rsrq":  s.Metric5GCurrentRSRQ,
+		"arfcn": s.Metric5GDownlinkARFCN,
+
+		"rsrp_strength_index":   s.Metric5GRSRPStrengthIndex,
+		"signal_strength_level": s.Metric5GSignalStrengthLevel,
+	}
+
+	s.Metric5GCellInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "5g",
+		Name:      "cell_info",
+		Help:      "Always 1, labeled with the band, PCI and ARFCN of the 5G cell currently attached to",
+	}, []string{"band", "pci", "arfcn"})
+
+	s.Metrics5GByCell = map[string]*prometheus.GaugeVec{
+		"snr": prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gomo",
+			Subsystem: "5g",
+			Name:      "snr_db",
+			Help:      "The current SNR of the 5G radio at this point in time. dB",
+		}, []string{"band", "pci"}),
+		"rsrp": prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gomo",
+			Subsystem: "5g",
+			Name:      "rsrp_dbm",
+			Help:      "The current RSRP of the 5G radio at this point in time. dBm",
+		}, []string{"band", "pci"}),
+		"rsrq": prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gomo",
+			Subsystem: "5g",
+			Name:      "rsrq_db",
+			Help:      "The current RSRQ of the 5G radio at this point in time. dB",
+		}, []string{"band", "pci"}),
+	}
+
+	// LTE
+	s.MetricLTEPhysicalCellID = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "physical_cell_id",
+		Help:      "The physical cell ID (PCI) of the cell the LTE radio is attached to. integer",
+	})
+
+	s.MetricLTEBandFrequency = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "band_frequency_hertz",
+		Help:      "The nominal frequency of the band the LTE radio is attached on. hertz",
+	})
+
+	s.MetricLTECurrentSNR = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "snr_db",
+		Help:      "The current SNR of the LTE radio at this point in time. dB",
+	})
+
+	s.MetricLTECurrentRSRP = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "rsrp_dbm",
+		Help:      "The current RSRP of the LTE radio at this point in time. dBm",
+	})
+
+	s.MetricLTECurrentRSRQ = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "rsrq_db",
+		Help:      "The current RSRQ of the LTE radio at this point in time. dB",
+	})
+
+	s.MetricLTEDownlinkEARFCN = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "downlink_earfcn",
+		Help:      "The downlink E-UTRA absolute radio frequency channel number of the LTE radio. integer",
+	})
+
+	s.MetricLTECurrentRSSI = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "rssi_dbm",
+		Help:      "The current RSSI of the LTE radio at this point in time. dBm",
+	})
+
+	s.MetricLTERSRPStrengthIndex = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "rsrp_strength_index",
+		Help:      "The RSRP strength index the trashcan reports for the LTE radio. 0-100",
+	})
+
+	s.MetricLTESignalStrengthLevel = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "signal_strength_level",
+		Help:      "The signal strength in bars the trashcan displays for the LTE radio. integer",
+	})
+
+	s.MetricsLTE = map[string]prometheus.Gauge{
+		"pci":   s.MetricLTEPhysicalCellID,
+		"band":  s.MetricLTEBandFrequency,
+		"snr":   s.MetricLTECurrentSNR,
+		"rsrp":  s.MetricLTECurrentRSRP,
+		"rsrq":  s.MetricLTECurrentRSRQ,
+		"rssi":  s.MetricLTECurrentRSSI,
+		"arfcn": s.MetricLTEDownlinkEARFCN,
+
+		"rsrp_strength_index":   s.MetricLTERSRPStrengthIndex,
+		"signal_strength_level": s.MetricLTESignalStrengthLevel,
+	}
+
+	s.MetricLTECellInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "lte",
+		Name:      "cell_info",
+		Help:      "Always 1, labeled with the band, PCI and EARFCN of the LTE cell currently attached to",
+	}, []string{"band", "pci", "arfcn"})
+
+	s.MetricsLTEByCell = map[string]*prometheus.GaugeVec{
+		"snr": prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gomo",
+			Subsystem: "lte",
+			Name:      "snr_db",
+			Help:      "The current SNR of the LTE radio at this point in time. dB",
+		}, []string{"band", "pci"}),
+		"rsrp": prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gomo",
+			Subsystem: "lte",
+			Name:      "rsrp_dbm",
+			Help:      "The current RSRP of the LTE radio at this point in time. dBm",
+		}, []string{"band", "pci"}),
+		"rsrq": prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gomo",
+			Subsystem: "lte",
+			Name:      "rsrq_db",
+			Help:      "The current RSRQ of the LTE radio at this point in time. dB",
+		}, []string{"band", "pci"}),
+		"rssi": prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gomo",
+			Subsystem: "lte",
+			Name:      "rssi_dbm",
+			Help:      "The current RSSI of the LTE radio at this point in time. dBm",
+		}, []string{"band", "pci"}),
+	}
+
+	// Signal Distribution
+	s.Metric5GSNRHistogram = signalHistogram("5g", "sampled_snr_db", "The distribution of the 5G radio's SNR sampled at every scrape, labeled with the band it was sampled on. dB", NRSNRBuckets)
+
+	s.Metric5GRSRPHistogram = signalHistogram("5g", "sampled_rsrp_dbm", "The distribution of the 5G radio's RSRP sampled at every scrape, labeled with the band it was sampled on. dBm", RSRPBuckets)
+
+	s.Metric5GRSRQHistogram = signalHistogram("5g", "sampled_rsrq_db", "The distribution of the 5G radio's RSRQ sampled at every scrape, labeled with the band it was sampled on. dB", RSRQBuckets)
+
+	s.MetricLTESNRHistogram = signalHistogram("lte", "sampled_snr_db", "The distribution of the LTE radio's SNR sampled at every scrape, labeled with the band it was sampled on. dB", LTESNRBuckets)
+
+	s.MetricLTERSRPHistogram = signalHistogram("lte", "sampled_rsrp_dbm", "The distribution of the LTE radio's RSRP sampled at every scrape, labeled with the band it was sampled on. dBm", RSRPBuckets)
+
+	s.MetricLTERSRQHistogram = signalHistogram("lte", "sampled_rsrq_db", "The distribution of the LTE radio's RSRQ sampled at every scrape, labeled with the band it was sampled on. dB", RSRQBuckets)
+
+	// Scrape
+	s.MetricScrapeUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "scrape",
+		Name:      "up",
+		Help:      "1 if the last scrape of the trashcan succeeded. integer bool",
+	})
+
+	s.MetricScrapeLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "scrape",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "The unix time of the last successful scrape of the trashcan. seconds",
+	})
+
+	s.MetricScrapeConsecutiveFailures = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "scrape",
+		Name:      "consecutive_failures",
+		Help:      "The number of scrapes of the trashcan that have failed in a row",
+	})
+
+	s.MetricsScrape = map[string]prometheus.Gauge{
+		"up":                   s.MetricScrapeUp,
+		"last_success":         s.MetricScrapeLastSuccess,
+		"consecutive_failures": s.MetricScrapeConsecutiveFailures,
+	}
+
+	s.MetricScrapeSuccesses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "scrape",
+		Name:      "success_total",
+		Help:      "The total number of successful scrapes of the trashcan",
+	})
+
+	s.MetricScrapeFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "scrape",
+		Name:      "failures_total",
+		Help:      "The total number of failed scrapes of the trashcan",
+	})
+
+	s.MetricsScrapeCounters = map[string]prometheus.Counter{
+		"success":  s.MetricScrapeSuccesses,
+		"failures": s.MetricScrapeFailures,
+	}
+
+	s.MetricScrapePollInterval = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "scrape",
+		Name:      "poll_interval_seconds",
+		Help:      "The interval the daemon currently polls the trashcan at. seconds",
+	})
+
+	s.MetricScrapeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gomo",
+		Subsystem: "scrape",
+		Name:      "duration_seconds",
+		Help:      "The distribution of how long each scrape of the trashcan took, failed or not. seconds",
+		Buckets:   ScrapeDurationBuckets,
+	})
+
+	// Misc
+	s.MetricConnectionStatus = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "connection_status",
+		Help:      "The reported connection status of the device. integer bool",
+	})
+
+	s.MetricCellularBytesSent = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "cell",
+		Name:      "sent_bytes_total",
+		Help:      "The total number of bytes sent over the cellular connection since the trashcan started. bytes",
+	})
+
+	s.MetricCellularBytesRecv = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "cell",
+		Name:      "received_bytes_total",
+		Help:      "The total number of bytes received over the cellular connection since the trashcan started. bytes",
+	})
+
+	s.MetricsMisc = map[string]Setter{
+		"connection_status": s.MetricConnectionStatus,
+		"bytes_sent":        s.MetricCellularBytesSent,
+		"bytes_recv":        s.MetricCellularBytesRecv,
+	}
+
+	// Ethernet
+	s.MetricEthernetEnabled = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ethernet",
+		Name:      "enabled",
+		Help:      "1 if the trashcan's ethernet port is enabled. integer bool",
+	})
+
+	s.MetricEthernetUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ethernet",
+		Name:      "up",
+		Help:      "1 if the trashcan reports its ethernet port up. integer bool",
+	})
+
+	s.MetricEthernetBytesSent = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "ethernet",
+		Name:      "sent_bytes_total",
+		Help:      "The total number of bytes sent out of the ethernet port since the trashcan started. bytes",
+	})
+
+	s.MetricEthernetBytesRecv = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "ethernet",
+		Name:      "received_bytes_total",
+		Help:      "The total number of bytes received on the ethernet port since the trashcan started. bytes",
+	})
+
+	s.MetricEthernetPacketsSent = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "ethernet",
+		Name:      "sent_packets_total",
+		Help:      "The total number of packets sent out of the ethernet port since the trashcan started",
+	})
+
+	s.MetricEthernetPacketsRecv = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "ethernet",
+		Name:      "received_packets_total",
+		Help:      "The total number of packets received on the ethernet port since the trashcan started",
+	})
+
+	s.MetricsEthernet = map[string]Setter{
+		"enabled":          s.MetricEthernetEnabled,
+		"up":               s.MetricEthernetUp,
+		"bytes_sent":       s.MetricEthernetBytesSent,
+		"bytes_recv":       s.MetricEthernetBytesRecv,
+		"packets_sent":     s.MetricEthernetPacketsSent,
+		"packets_received": s.MetricEthernetPacketsRecv,
+	}
+
+	// Carrier Aggregation
+	s.MetricCADownlinkCarriers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ca",
+		Name:      "downlink_carriers",
+		Help:      "The number of secondary carriers aggregated on the downlink. integer",
+	})
+
+	s.MetricCAUplinkCarriers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ca",
+		Name:      "uplink_carriers",
+		Help:      "The number of secondary carriers aggregated on the uplink. integer",
+	})
+
+	s.MetricsCA = map[string]prometheus.Gauge{
+		"downlink_carriers": s.MetricCADownlinkCarriers,
+		"uplink_carriers":   s.MetricCAUplinkCarriers,
+	}
+
+	// APN
+	s.MetricAPNEnabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "apn",
+		Name:      "enabled",
+		Help:      "1 if the APN is enabled. integer bool",
+	}, []string{"apn", "service_type"})
+
+	s.MetricAPNConnectionState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "apn",
+		Name:      "connection_state",
+		Help:      "The connection state the trashcan reports for the APN. integer",
+	}, []string{"apn", "service_type"})
+
+	s.MetricsAPN = map[string]*prometheus.GaugeVec{
+		"enabled":          s.MetricAPNEnabled,
+		"connection_state": s.MetricAPNConnectionState,
+	}
+
+	// Ping
+	s.MetricPingLossRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "loss_ratio",
+		Help:      "The ratio of ping packets lost to the target during the last check. 0-1",
+	}, []string{"target", "family"})
+
+	s.MetricPingLossStreak = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "loss_streak",
+		Help:      "The number of consecutive ping packets currently lost to the target, carried across checks",
+	}, []string{"target", "family"})
+
+	s.MetricPingLossStreakMax = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "loss_streak_max",
+		Help:      "The longest run of consecutive ping packets lost to the target during the last check",
+	}, []string{"target", "family"})
+
+	s.MetricPingRTTMin = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "rtt_min_seconds",
+		Help:      "The minimum round trip time to the target during the last check. seconds",
+	}, []string{"target", "family"})
+
+	s.MetricPingRTTAvg = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "rtt_avg_seconds",
+		Help:      "The average round trip time to the target during the last check. seconds",
+	}, []string{"target", "family"})
+
+	s.MetricPingRTTMax = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "rtt_max_seconds",
+		Help:      "The maximum round trip time to the target during the last check. seconds",
+	}, []string{"target", "family"})
+
+	s.MetricPingJitter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "jitter_seconds",
+		Help:      "The mean difference between consecutive round trip times to the target during the last check. seconds",
+	}, []string{"target", "family"})
+
+	s.MetricPingUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "up",
+		Help:      "Whether the last ping check to the target completed without error. integer bool",
+	}, []string{"target", "family"})
+
+	s.MetricsPing = map[string]*prometheus.GaugeVec{
+		"loss_ratio":      s.MetricPingLossRatio,
+		"loss_streak":     s.MetricPingLossStreak,
+		"loss_streak_max": s.MetricPingLossStreakMax,
+		"rtt_min":         s.MetricPingRTTMin,
+		"rtt_avg":         s.MetricPingRTTAvg,
+		"rtt_max":         s.MetricPingRTTMax,
+		"jitter":          s.MetricPingJitter,
+		"up":              s.MetricPingUp,
+	}
+
+	s.MetricPingRTT = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "rtt_seconds",
+		Help:      "The distribution of every ping round trip time to the target. seconds",
+		Buckets:   PingRTTBuckets,
+	}, []string{"target", "family"})
+
+	s.MetricsPingHistograms = map[string]*prometheus.HistogramVec{
+		"rtt": s.MetricPingRTT,
+	}
+
+	s.MetricPingPacketsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "packets_sent_total",
+		Help:      "The total number of ping packets sent to the target",
+	}, []string{"target", "family"})
+
+	s.MetricPingPacketsLost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "ping",
+		Name:      "packets_lost_total",
+		Help:      "The total number of ping packets to the target which received no reply",
+	}, []string{"target", "family"})
+
+	s.MetricsPingCounters = map[string]*prometheus.CounterVec{
+		"packets_sent": s.MetricPingPacketsSent,
+		"packets_lost": s.MetricPingPacketsLost,
+	}
+
+	// DNS
+	s.MetricDNSLatencyAvg = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "dns",
+		Name:      "lookup_avg_seconds",
+		Help:      "The average successful lookup time of the name through the resolver during the last check. seconds",
+	}, []string{"resolver", "name", "family"})
+
+	s.MetricDNSLatencyMax = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "dns",
+		Name:      "lookup_max_seconds",
+		Help:      "The slowest successful lookup time of the name through the resolver during the last check. seconds",
+	}, []string{"resolver", "name", "family"})
+
+	s.MetricDNSFailureRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "dns",
+		Name:      "lookup_failure_ratio",
+		Help:      "The ratio of failed lookups of the name through the resolver during the last check. 0-1",
+	}, []string{"resolver", "name", "family"})
+
+	s.MetricDNSMismatch = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "dns",
+		Name:      "answer_mismatch",
+		Help:      "Whether the resolver's answers for the name shared no address with the other resolvers. integer bool",
+	}, []string{"resolver", "name", "family"})
+
+	s.MetricsDNS = map[string]*prometheus.GaugeVec{
+		"latency_avg":   s.MetricDNSLatencyAvg,
+		"latency_max":   s.MetricDNSLatencyMax,
+		"failure_ratio": s.MetricDNSFailureRatio,
+		"mismatch":      s.MetricDNSMismatch,
+	}
+
+	// TCP
+	s.MetricTCPPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "tcp",
+		Name:      "phase_seconds",
+		Help:      "The time spent in each phase (dns, connect, total) of the last TCP connect check to the target. seconds",
+	}, []string{"target", "family", "phase"})
+
+	s.MetricTCPUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "tcp",
+		Name:      "up",
+		Help:      "Whether the last TCP connect check to the target succeeded. integer bool",
+	}, []string{"target", "family"})
+
+	s.MetricsTCP = map[string]*prometheus.GaugeVec{
+		"phase": s.MetricTCPPhase,
+		"up":    s.MetricTCPUp,
+	}
+
+	// HTTP
+	s.MetricHTTPPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "http",
+		Name:      "phase_seconds",
+		Help:      "The time spent in each phase (dns, connect, tls, ttfb, total) of the last HTTP check to the url. seconds",
+	}, []string{"url", "phase"})
+
+	s.MetricHTTPStatusCode = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "http",
+		Name:      "status_code",
+		Help:      "The HTTP status code returned by the url during the last check, 0 on connection failure",
+	}, []string{"url"})
+
+	s.MetricHTTPUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "http",
+		Name:      "up",
+		Help:      "Whether the last HTTP check to the url returned an expected status code. integer bool",
+	}, []string{"url"})
+
+	s.MetricsHTTP = map[string]*prometheus.GaugeVec{
+		"phase":       s.MetricHTTPPhase,
+		"status_code": s.MetricHTTPStatusCode,
+		"up":          s.MetricHTTPUp,
+	}
+
+	// Speed Test
+	s.MetricSpeedTestThroughput = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "speedtest",
+		Name:      "throughput_bits_per_second",
+		Help:      "The throughput of the last speed test, labeled with the 5G band and PCI (LTE when 5G is not attached) at the start of the test. bits/s",
+	}, []string{"direction", "band", "pci"})
+
+	s.MetricSpeedTestStreamStdDev = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "speedtest",
+		Name:      "stream_stddev_bits_per_second",
+		Help:      "The standard deviation of per stream throughput during the last speed test. bits/s",
+	}, []string{"direction"})
+
+	s.MetricSpeedTestLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "speedtest",
+		Name:      "latency_seconds",
+		Help:      "The TCP connect latency to the test endpoint while idle or under load during the last speed test. seconds",
+	}, []string{"direction", "stat"})
+
+	s.MetricSpeedTestRadioSNR = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "speedtest",
+		Name:      "radio_snr_db",
+		Help:      "The SNR of each radio at the start of the last speed test. dB",
+	}, []string{"radio", "band", "pci"})
+
+	s.MetricSpeedTestLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "speedtest",
+		Name:      "last_run_timestamp_seconds",
+		Help:      "The unix time the last speed test in each direction completed. seconds",
+	}, []string{"direction"})
+
+	s.MetricsSpeedTest = map[string]*prometheus.GaugeVec{
+		"throughput":    s.MetricSpeedTestThroughput,
+		"stream_stddev": s.MetricSpeedTestStreamStdDev,
+		"latency":       s.MetricSpeedTestLatency,
+		"radio_snr":     s.MetricSpeedTestRadioSNR,
+		"last_run":      s.MetricSpeedTestLastRun,
+	}
+
+	// Bufferbloat
+	s.MetricBufferbloatLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "bufferbloat",
+		Name:      "latency_seconds",
+		Help:      "The average ping round trip time to the ping targets while idle or while the link was saturated in a direction during the last bufferbloat test. seconds",
+	}, []string{"load"})
+
+	s.MetricBufferbloatIncrease = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "bufferbloat",
+		Name:      "latency_increase_seconds",
+		Help:      "The increase in average ping round trip time while the link was saturated in a direction during the last bufferbloat test. seconds",
+	}, []string{"load"})
+
+	s.MetricBufferbloatGrade = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "bufferbloat",
+		Name:      "grade",
+		Help:      "Always 1, labeled with the grade of the last bufferbloat test in each direction and overall",
+	}, []string{"load", "grade"})
+
+	s.MetricsBufferbloat = map[string]*prometheus.GaugeVec{
+		"latency":  s.MetricBufferbloatLatency,
+		"increase": s.MetricBufferbloatIncrease,
+		"grade":    s.MetricBufferbloatGrade,
+	}
+
+	// Outage
+	s.MetricOutagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "outage",
+		Name:      "outages_total",
+		Help:      "The total number of outages that began, by classification",
+	}, []string{"classification"})
+
+	s.MetricOutageDowntime = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "outage",
+		Name:      "downtime_seconds_total",
+		Help:      "The total duration of outages that have ended, by classification. seconds",
+	}, []string{"classification"})
+
+	s.MetricsOutageCounters = map[string]*prometheus.CounterVec{
+		"outages":  s.MetricOutagesTotal,
+		"downtime": s.MetricOutageDowntime,
+	}
+
+	s.MetricOutageState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "outage",
+		Name:      "state",
+		Help:      "1 for the current connectivity state and 0 for every other state",
+	}, []string{"state"})
+
+	s.MetricOutageCurrentDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "outage",
+		Name:      "current_duration_seconds",
+		Help:      "How long the ongoing outage has lasted, 0 when there is none. seconds",
+	}, []string{})
+
+	s.MetricsOutage = map[string]*prometheus.GaugeVec{
+		"state":            s.MetricOutageState,
+		"current_duration": s.MetricOutageCurrentDuration,
+	}
+
+	// LAN vs WAN
+	s.MetricLinkLoss = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "link",
+		Name:      "loss_ratio",
+		Help:      "The ping loss attributed to the local network (host to gateway) or the WAN (beyond the gateway) during the last check",
+	}, []string{"segment"})
+
+	s.MetricLinkLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "link",
+		Name:      "latency_seconds",
+		Help:      "The average ping round trip time to the gateway for the lan segment, or added beyond the gateway for the wan segment, during the last check. seconds",
+	}, []string{"segment"})
+
+	s.MetricLinkJitter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "link",
+		Name:      "jitter_seconds",
+		Help:      "The ping jitter to the gateway for the lan segment, or to the internet targets for the wan segment, during the last check. seconds",
+	}, []string{"segment"})
+
+	s.MetricLinkVerdict = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "link",
+		Name:      "verdict",
+		Help:      "1 for the segment judged at fault during the last check (healthy, lan, wan, both or unknown) and 0 for every other verdict",
+	}, []string{"verdict"})
+
+	s.MetricsLink = map[string]*prometheus.GaugeVec{
+		"loss":    s.MetricLinkLoss,
+		"latency": s.MetricLinkLatency,
+		"jitter":  s.MetricLinkJitter,
+		"verdict": s.MetricLinkVerdict,
+	}
+
+	// Path MTU and CGNAT
+	s.MetricMTUPath = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "mtu",
+		Name:      "path_bytes",
+		Help:      "The largest packet that reached the target with the don't fragment bit set during the last check, 0 if discovery failed. bytes",
+	}, []string{"target"})
+
+	s.MetricsMTU = map[string]*prometheus.GaugeVec{
+		"path": s.MetricMTUPath,
+	}
+
+	s.MetricCGNATDetected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "cgnat",
+		Name:      "detected",
+		Help:      "1 if the connection is behind carrier grade NAT",
+	}, []string{})
+
+	s.MetricCGNATSharedSpace = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "cgnat",
+		Name:      "shared_address_space",
+		Help:      "1 if the trashcan's WAN IPv4 address is in the 100.64.0.0/10 shared address space",
+	}, []string{})
+
+	s.MetricCGNATTranslated = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "cgnat",
+		Name:      "translated",
+		Help:      "1 if the public address seen by the echo endpoint differs from the trashcan's WAN IPv4 address",
+	}, []string{})
+
+	s.MetricCGNATInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "cgnat",
+		Name:      "info",
+		Help:      "Always 1, labeled with the trashcan's WAN IPv4 address and the public address seen by the echo endpoint",
+	}, []string{"wan_address", "public_address"})
+
+	s.MetricsCGNAT = map[string]*prometheus.GaugeVec{
+		"detected":     s.MetricCGNATDetected,
+		"shared_space": s.MetricCGNATSharedSpace,
+		"translated":   s.MetricCGNATTranslated,
+		"info":         s.MetricCGNATInfo,
+	}
+
+	// NAT
+	s.MetricNATInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "nat",
+		Name:      "info",
+		Help:      "Always 1, labeled with the NAT mapping and filtering behavior, classic type and console rating found by the last STUN check",
+	}, []string{"mapping", "filtering", "type", "console"})
+
+	s.MetricNATPortPreserved = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "nat",
+		Name:      "port_preserved",
+		Help:      "1 if the NAT kept the local port in the mapped address during the last STUN check",
+	}, []string{})
+
+	s.MetricSTUNRTT = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "stun",
+		Name:      "rtt_seconds",
+		Help:      "The binding request round trip time to the STUN server during the last check. seconds",
+	}, []string{"server"})
+
+	s.MetricSTUNUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "stun",
+		Name:      "up",
+		Help:      "1 if the STUN server answered a binding request during the last check",
+	}, []string{"server"})
+
+	s.MetricsNAT = map[string]*prometheus.GaugeVec{
+		"info":           s.MetricNATInfo,
+		"port_preserved": s.MetricNATPortPreserved,
+		"stun_rtt":       s.MetricSTUNRTT,
+		"stun_up":        s.MetricSTUNUp,
+	}
+
+	// Dual Stack
+	s.MetricFamilyUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "family",
+		Name:      "up",
+		Help:      "1 if any ping or TCP probe over the address family succeeded during the last check",
+	}, []string{"family"})
+
+	s.MetricFamilyBroken = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "family",
+		Name:      "broken",
+		Help:      "1 if every probe over the address family failed while the other family worked during the last check",
+	}, []string{"family"})
+
+	s.MetricFamilyLossRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "family",
+		Name:      "ping_loss_ratio",
+		Help:      "The mean ping loss ratio over the address family during the last check. 0-1",
+	}, []string{"family"})
+
+	s.MetricFamilyLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "family",
+		Name:      "ping_rtt_avg_seconds",
+		Help:      "The mean average ping round trip time over the address family during the last check. seconds",
+	}, []string{"family"})
+
+	s.MetricFamilyConnect = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "family",
+		Name:      "tcp_connect_seconds",
+		Help:      "The mean TCP connect time over the address family during the last check. seconds",
+	}, []string{"family"})
+
+	s.MetricFamilyDNSFailureRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "family",
+		Name:      "dns_failure_ratio",
+		Help:      "The ratio of failed A (ip4) or AAAA (ip6) lookups during the last check. 0-1",
+	}, []string{"family"})
+
+	s.MetricsFamily = map[string]*prometheus.GaugeVec{
+		"up":                s.MetricFamilyUp,
+		"broken":            s.MetricFamilyBroken,
+		"loss_ratio":        s.MetricFamilyLossRatio,
+		"latency":           s.MetricFamilyLatency,
+		"connect":           s.MetricFamilyConnect,
+		"dns_failure_ratio": s.MetricFamilyDNSFailureRatio,
+	}
+
+	// Multi-WAN
+	s.MetricWANUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "up",
+		Help:      "1 if any ping or TCP probe bound to the WAN succeeded during the last check",
+	}, []string{"wan"})
+
+	s.MetricWANLossRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "loss_ratio",
+		Help:      "The mean ping loss ratio across the WAN's targets during the last check. 0-1",
+	}, []string{"wan"})
+
+	s.MetricWANLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "rtt_avg_seconds",
+		Help:      "The mean average ping round trip time across the WAN's targets during the last check. seconds",
+	}, []string{"wan"})
+
+	s.MetricWANJitter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "jitter_seconds",
+		Help:      "The mean ping jitter across the WAN's targets during the last check. seconds",
+	}, []string{"wan"})
+
+	s.MetricWANConnect = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "tcp_connect_seconds",
+		Help:      "The mean TCP connect time across the WAN's targets during the last check. seconds",
+	}, []string{"wan"})
+
+	s.MetricWANTargetLossRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "target_loss_ratio",
+		Help:      "The ratio of ping packets lost to the target through the WAN during the last check. 0-1",
+	}, []string{"wan", "target"})
+
+	s.MetricWANTargetRTT = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "target_rtt_avg_seconds",
+		Help:      "The average ping round trip time to the target through the WAN during the last check. seconds",
+	}, []string{"wan", "target"})
+
+	s.MetricWANTargetTCPUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "wan",
+		Name:      "target_tcp_up",
+		Help:      "Whether the last TCP connect check to the target through the WAN succeeded. integer bool",
+	}, []string{"wan", "target"})
+
+	s.MetricsWAN = map[string]*prometheus.GaugeVec{
+		"up":          s.MetricWANUp,
+		"loss_ratio":  s.MetricWANLossRatio,
+		"latency":     s.MetricWANLatency,
+		"jitter":      s.MetricWANJitter,
+		"connect":     s.MetricWANConnect,
+		"target_loss": s.MetricWANTargetLossRatio,
+		"target_rtt":  s.MetricWANTargetRTT,
+		"target_tcp":  s.MetricWANTargetTCPUp,
+	}
+
+	s.MetricSLOObjective = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "slo",
+		Name:      "objective_ratio",
+		Help:      "The configured target ratio of good events. 0-1",
+	}, []string{"slo"})
+
+	s.MetricSLOCompliance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "slo",
+		Name:      "compliance_ratio",
+		Help:      "The ratio of good events over the SLO's window. 0-1",
+	}, []string{"slo"})
+
+	s.MetricSLOBudgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "slo",
+		Name:      "error_budget_remaining_ratio",
+		Help:      "The share of the SLO's error budget left over its window, negative once exhausted",
+	}, []string{"slo"})
+
+	s.MetricSLOBurnRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "slo",
+		Name:      "burn_rate",
+		Help:      "How fast the error budget was spent over the last hour, 1 spends exactly the budget over the window",
+	}, []string{"slo"})
+
+	s.MetricSLOMet = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "slo",
+		Name:      "met",
+		Help:      "1 if compliance over the window meets the objective. integer bool",
+	}, []string{"slo"})
+
+	s.MetricSLOEvents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "slo",
+		Name:      "events",
+		Help:      "The number of events recorded over the SLO's window",
+	}, []string{"slo"})
+
+	s.MetricSLOGoodEvents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "slo",
+		Name:      "good_events",
+		Help:      "The number of good events recorded over the SLO's window",
+	}, []string{"slo"})
+
+	s.MetricsSLO = map[string]*prometheus.GaugeVec{
+		"objective":        s.MetricSLOObjective,
+		"compliance":       s.MetricSLOCompliance,
+		"budget_remaining": s.MetricSLOBudgetRemaining,
+		"burn_rate":        s.MetricSLOBurnRate,
+		"met":              s.MetricSLOMet,
+		"events":           s.MetricSLOEvents,
+		"good_events":      s.MetricSLOGoodEvents,
+	}
+
+	// Push
+	s.MetricPushSent = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "push",
+		Name:      "sent_requests_total",
+		Help:      "The total number of pushes or write requests accepted by the push endpoint",
+	})
+
+	s.MetricPushFailed = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "push",
+		Name:      "failed_requests_total",
+		Help:      "The total number of attempts to push to the push endpoint which failed, retries included",
+	})
+
+	s.MetricPushDropped = NewReportedCounter(prometheus.CounterOpts{
+		Namespace: "gomo",
+		Subsystem: "push",
+		Name:      "dropped_samples_total",
+		Help:      "The total number of samples dropped unsent because the endpoint rejected them or the buffer was full",
+	})
+
+	s.MetricPushBuffered = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "push",
+		Name:      "buffered_samples",
+		Help:      "The number of samples waiting to be sent to the remote write or InfluxDB endpoint",
+	})
+
+	s.MetricPushLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gomo",
+		Subsystem: "push",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "The unix time of the last request accepted by the push endpoint. seconds",
+	})
+
+	s.MetricsPush = map[string]Setter{
+		"sent":         s.MetricPushSent,
+		"failed":       s.MetricPushFailed,
+		"dropped":      s.MetricPushDropped,
+		"buffered":     s.MetricPushBuffered,
+		"last_success": s.MetricPushLastSuccess,
+	}
+
+	s.RadioMetrics = radioMetrics(s)
+	s.RadioMetricsByCell = radioMetricsByCell(s)
+	s.RadioHistograms = radioHistograms(s)
+	s.LegacyRadioMetrics = legacyRadioMetrics()
+
+	return s
 }
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Setter is a metric set to a value read from a scrape, a prometheus.Gauge or
+// a ReportedCounter
+type Setter interface {
+	prometheus.Collector
+	Set(float64)
+}
+
+// RadioMetric maps one value of a trashcan scrape onto a metric. Value returns
+// false when the scrape doesn't carry it, leaving the metric as it was
+type RadioMetric struct {
+	Group  string
+	Metric Setter
+	Value  func(*models.FastmileRadioStatus) (float64, bool)
+}
+
+// radioMetrics returns every metric of s set from a trashcan scrape
+func radioMetrics(s *Set) []RadioMetric {
+	return []RadioMetric{
+		{Group5G, s.Metric5GPhysicalCellID, nr(func(c *models.Cell5GStat) float64 { return c.ID() })},
+		{Group5G, s.Metric5GBandFrequency, nr(func(c *models.Cell5GStat) float64 { return math.Round(c.Band64() * 1e9) })},
+		{Group5G, s.Metric5GCurrentSNR, nr(func(c *models.Cell5GStat) float64 { return c.SNRCurrent })},
+		{Group5G, s.Metric5GCurrentRSRP, nr(func(c *models.Cell5GStat) float64 { return c.RSRPCurrent })},
+		{Group5G, s.Metric5GCurrentRSRQ, nr(func(c *models.Cell5GStat) float64 { return c.RSRQCurrent })},
+		{Group5G, s.Metric5GDownlinkARFCN, nr(func(c *models.Cell5GStat) float64 { return c.DownlinkNRARFCN })},
+		{Group5G, s.Metric5GRSRPStrengthIndex, nr(func(c *models.Cell5GStat) float64 { return c.RSRPStrengthIndexCurrent })},
+		{Group5G, s.Metric5GSignalStrengthLevel, nr(func(c *models.Cell5GStat) float64 { return c.SignalStrengthLevel })},
+
+		{GroupLTE, s.MetricLTEPhysicalCellID, lte(func(c *models.CellLTEStat) float64 { return c.ID() })},
+		{GroupLTE, s.MetricLTEBandFrequency, lte(func(c *models.CellLTEStat) float64 { return math.Round(c.Band64() * 1e9) })},
+		{GroupLTE, s.MetricLTECurrentSNR, lte(func(c *models.CellLTEStat) float64 { return c.SNRCurrent })},
+		{GroupLTE, s.MetricLTECurrentRSRP, lte(func(c *models.CellLTEStat) float64 { return c.RSRPCurrent })},
+		{GroupLTE, s.MetricLTECurrentRSRQ, lte(func(c *models.CellLTEStat) float64 { return c.RSRQCurrent })},
+		{GroupLTE, s.MetricLTECurrentRSSI, lte(func(c *models.CellLTEStat) float64 { return c.RSSICurrent })},
+		{GroupLTE, s.MetricLTEDownlinkEARFCN, lte(func(c *models.CellLTEStat) float64 { return c.DownlinkEarfcn })},
+		{GroupLTE, s.MetricLTERSRPStrengthIndex, lte(func(c *models.CellLTEStat) float64 { return c.RSRPStrengthIndexCurrent })},
+		{GroupLTE, s.MetricLTESignalStrengthLevel, lte(func(c *models.CellLTEStat) float64 { return c.SignalStrengthLevel })},
+
+		{GroupMisc, s.MetricConnectionStatus, connection(func(c *models.ConnectionStatus) float64 { return float64(c.ConnectionStatus) })},
+		{GroupMisc, s.MetricCellularBytesSent, cellular(func(c *models.CellularStats) float64 { return float64(c.BytesSent) })},
+		{GroupMisc, s.MetricCellularBytesRecv, cellular(func(c *models.CellularStats) float64 { return float64(c.BytesReceived) })},
+
+		{GroupEthernet, s.MetricEthernetEnabled, ethernet(func(e *models.EthernetStats) float64 { return float64(e.Enable) })},
+		{GroupEthernet, s.MetricEthernetUp, ethernet(func(e *models.EthernetStats) float64 { return boolFloat(strings.EqualFold(e.Status, "up")) })},
+		{GroupEthernet, s.MetricEthernetBytesSent, ethernetStat(func(e *models.EthernetStatsStat) float64 { return float64(e.BytesSent) })},
+		{GroupEthernet, s.MetricEthernetBytesRecv, ethernetStat(func(e *models.EthernetStatsStat) float64 { return float64(e.BytesReceived) })},
+		{GroupEthernet, s.MetricEthernetPacketsSent, ethernetStat(func(e *models.EthernetStatsStat) float64 { return float64(e.PacketsSent) })},
+		{GroupEthernet, s.MetricEthernetPacketsRecv, ethernetStat(func(e *models.EthernetStatsStat) float64 { return float64(e.PacketsReceived) })},
+
+		{GroupCA, s.MetricCADownlinkCarriers, ca(func(c *models.CellCAStats) float64 { return float64(c.DLCarrierAggregationNumberOfEntries) })},
+		{GroupCA, s.MetricCAUplinkCarriers, ca(func(c *models.CellCAStats) float64 { return float64(c.ULCarrierAggregationNumberOfEntries) })},
+	}
+}
+
+// radioMetricsByCell returns the signal gauges of s labeled by band and PCI
+// keyed by the RadioMetrics they replace when band labels are enabled
+func radioMetricsByCell(s *Set) map[Setter]*prometheus.GaugeVec {
+	return map[Setter]*prometheus.GaugeVec{
+		s.Metric5GCurrentSNR:  s.Metrics5GByCell["snr"],
+		s.Metric5GCurrentRSRP: s.Metrics5GByCell["rsrp"],
+		s.Metric5GCurrentRSRQ: s.Metrics5GByCell["rsrq"],
+
+		s.MetricLTECurrentSNR:  s.MetricsLTEByCell["snr"],
+		s.MetricLTECurrentRSRP: s.MetricsLTEByCell["rsrp"],
+		s.MetricLTECurrentRSRQ: s.MetricsLTEByCell["rsrq"],
+		s.MetricLTECurrentRSSI: s.MetricsLTEByCell["rssi"],
+	}
+}
+
+// RadioHistogram maps one signal value of a radio onto a histogram labeled by
+// the band the radio is attached on
+type RadioHistogram struct {
+	Radio     string
+	Histogram *prometheus.HistogramVec
+	Value     func(*models.FastmileRadioStatus) (float64, bool)
+}
+
+// radioHistograms returns every signal distribution of s observed from a
+// trashcan scrape
+func radioHistograms(s *Set) []RadioHistogram {
+	return []RadioHistogram{
+		{Group5G, s.Metric5GSNRHistogram, nr(func(c *models.Cell5GStat) float64 { return c.SNRCurrent })},
+		{Group5G, s.Metric5GRSRPHistogram, nr(func(c *models.Cell5GStat) float64 { return c.RSRPCurrent })},
+		{Group5G, s.Metric5GRSRQHistogram, nr(func(c *models.Cell5GStat) float64 { return c.RSRQCurrent })},
+
+		{GroupLTE, s.MetricLTESNRHistogram, lte(func(c *models.CellLTEStat) float64 { return c.SNRCurrent })},
+		{GroupLTE, s.MetricLTERSRPHistogram, lte(func(c *models.CellLTEStat) float64 { return c.RSRPCurrent })},
+		{GroupLTE, s.MetricLTERSRQHistogram, lte(func(c *models.CellLTEStat) float64 { return c.RSRQCurrent })},
+	}
+}
+
+func nr(f func(*models.Cell5GStat) float64) func(*models.FastmileRadioStatus) (float64, bool) {
+	return func(s *models.FastmileRadioStatus) (float64, bool) {
+		if len(s.Cell5GStats) == 0 || s.Cell5GStats[0] == nil || s.Cell5GStats[0].Stat == nil {
+			return 0, false
+		}
+		return f(s.Cell5GStats[0].Stat), true
+	}
+}
+
+func lte(f func(*models.CellLTEStat) float64) func(*models.FastmileRadioStatus) (float64, bool) {
+	return func(s *models.FastmileRadioStatus) (float64, bool) {
+		if len(s.CellLTEStats) == 0 || s.CellLTEStats[0] == nil || s.CellLTEStats[0].Stat == nil {
+			return 0, false
+		}
+		return f(s.CellLTEStats[0].Stat), true
+	}
+}
+
+func connection(f func(*models.ConnectionStatus) float64) func(*models.FastmileRadioStatus) (float64, bool) {
+	return func(s *models.FastmileRadioStatus) (float64, bool) {
+		if len(s.ConnectionStatus) == 0 || s.ConnectionStatus[0] == nil {
+			return 0, false
+		}
+		return f(s.ConnectionStatus[0]), true
+	}
+}
+
+func cellular(f func(*models.CellularStats) float64) func(*models.FastmileRadioStatus) (float64, bool) {
+	return func(s *models.FastmileRadioStatus) (float64, bool) {
+		if len(s.CellularStats) == 0 || s.CellularStats[0] == nil {
+			return 0, false
+		}
+		return f(s.CellularStats[0]), true
+	}
+}
+
+func ethernet(f func(*models.EthernetStats) float64) func(*models.FastmileRadioStatus) (float64, bool) {
+	return func(s *models.FastmileRadioStatus) (float64, bool) {
+		if len(s.EthernetStats) == 0 || s.EthernetStats[0] == nil {
+			return 0, false
+		}
+		return f(s.EthernetStats[0]), true
+	}
+}
+
+func ethernetStat(f func(*models.EthernetStatsStat) float64) func(*models.FastmileRadioStatus) (float64, bool) {
+	return func(s *models.FastmileRadioStatus) (float64, bool) {
+		if len(s.EthernetStats) == 0 || s.EthernetStats[0] == nil || s.EthernetStats[0].Stat == nil {
+			return 0, false
+		}
+		return f(s.EthernetStats[0].Stat), true
+	}
+}
+
+func ca(f func(*models.CellCAStats) float64) func(*models.FastmileRadioStatus) (float64, bool) {
+	return func(s *models.FastmileRadioStatus) (float64, bool) {
+		if len(s.CellCAStats) == 0 || s.CellCAStats[0] == nil {
+			return 0, false
+		}
+		return f(s.CellCAStats[0]), true
+	}
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/asciifaceman/gomo/pkg/helpers"
 	"github.com/asciifaceman/gomo/pkg/radiofreq"
@@ -30,8 +31,10 @@ const (
 )
 
 type FastmileReturn struct {
-	Error error
-	Body  *FastmileRadioStatus
+	Error    error
+	Body     *FastmileRadioStatus
+	Start    time.Time
+	Duration time.Duration
 }
 
 // StatLTE returns the attached LTE stats
@@ -3,13 +3,240 @@ package models
 import "time"
 
 type PingReportReturn struct {
-	Error error
-	Body  *PingReport
+	Hostname string
+	Family   string
+	NoRecord bool
+	Error    error
+	Body     *PingReport
 }
 
 type PingReport struct {
 	Hostname        string
+	IPAddr          string
 	PacketsSent     int
+	PacketsRecv     int
 	PacketLoss      float64
+	MinResponseTime time.Duration
 	AvgResponseTime time.Duration
+	MaxResponseTime time.Duration
+	Jitter          time.Duration
+	Rtts            []time.Duration
+	LossStreak      int
+	LossStreakMax   int
+}
+
+type DNSReport struct {
+	Resolver    string
+	Address     string
+	Name        string
+	Family      string
+	NoRecord    bool
+	Lookups     int
+	Failures    int
+	FailureRate float64
+	MinLatency  time.Duration
+	AvgLatency  time.Duration
+	MaxLatency  time.Duration
+	Answers     []string
+	Mismatch    bool
+	Error       string
+}
+
+// StatusReport collects the results of a single run of every configured status check
+type StatusReport struct {
+	Start    time.Time
+	Pings    []*PingReportReturn
+	DNS      []*DNSReport
+	TCP      []*TCPReport
+	HTTP     []*HTTPReport
+	Gateway  *PingReportReturn
+	Split    *LinkSplit
+	MTU      []*MTUReport
+	Echo     *EchoReport
+	NAT      *NATReport
+	Families []*FamilyReport
+	WANs     []*WANReport
+}
+
+// WANReport is the result of ping and TCP probes bound to a single uplink.
+// Source is the configured address or interface and Address the local address
+// probes were sent from
+type WANReport struct {
+	Name    string
+	Source  string
+	Address string
+	Pings   []*PingReportReturn
+	TCP     []*TCPReport
+	Loss    float64
+	Latency time.Duration
+	Jitter  time.Duration
+	Connect time.Duration
+	Up      bool
+	Error   string
+}
+
+// FamilyReport summarizes the probes run over a single address family. Probes
+// against targets with no address in the family are left out
+type FamilyReport struct {
+	Family         string
+	Probes         int
+	Succeeded      int
+	Pings          int
+	Loss           float64
+	Latency        time.Duration
+	Connect        time.Duration
+	DNSFailures    int
+	DNSFailureRate float64
+	Reachable      bool
+	Broken         bool
+	Reason         string
+}
+
+// MTUReport is the result of path MTU discovery toward a single target
+type MTUReport struct {
+	Target  string
+	Address string
+	MTU     int
+	Probes  int
+	Error   string
+}
+
+// EchoReport is the public address an echo endpoint saw the request come from
+type EchoReport struct {
+	URL     string
+	Address string
+	Error   string
+}
+
+// CGNATReport compares the trashcan's WAN address with the public address
+// seen by an echo endpoint to tell if the connection is behind carrier grade NAT
+type CGNATReport struct {
+	WANAddress    string
+	PublicAddress string
+	SharedSpace   bool
+	Translated    bool
+	CGNAT         bool
+	Error         string
+}
+
+// SegmentStats is the loss and latency attributed to one segment of the path
+type SegmentStats struct {
+	Loss    float64
+	Latency time.Duration
+	Jitter  time.Duration
+}
+
+// LinkSplit separates the local network (host to gateway) from the WAN
+// (gateway to internet targets) along with a verdict on which is at fault
+type LinkSplit struct {
+	LAN     SegmentStats
+	WAN     SegmentStats
+	Verdict string
+	Reason  string
+}
+
+// ProbePhases breaks a connection oriented probe down into its individual phases
+type ProbePhases struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	Total   time.Duration
+}
+
+type TCPReport struct {
+	Target   string
+	Family   string
+	NoRecord bool
+	Address  string
+	Up       bool
+	Phases   ProbePhases
+	Error    string
+}
+
+type HTTPReport struct {
+	URL        string
+	StatusCode int
+	Expected   bool
+	Up         bool
+	Phases     ProbePhases
+	Error      string
+}
+
+// RadioTag identifies the radio conditions a measurement was taken under
+type RadioTag struct {
+	Band string
+	PCI  string
+	SNR  float64
+	RSRP float64
+}
+
+// LatencySummary summarizes a set of latency samples
+type LatencySummary struct {
+	Samples int
+	Min     time.Duration
+	Avg     time.Duration
+	Max     time.Duration
+	Jitter  time.Duration
+}
+
+type ThroughputReport struct {
+	Direction     string
+	Bytes         int64
+	Duration      time.Duration
+	BitsPerSecond float64
+	Streams       []float64
+	StreamStdDev  float64
+	Latency       LatencySummary
+	Error         string
+}
+
+type SpeedTestReport struct {
+	Start       time.Time
+	RadioError  string
+	Radio5G     RadioTag
+	RadioLTE    RadioTag
+	IdleLatency LatencySummary
+	Download    *ThroughputReport
+	Upload      *ThroughputReport
+}
+
+type LoadedLatencyReport struct {
+	Direction  string
+	Latency    LatencySummary
+	Increase   time.Duration
+	Grade      string
+	Throughput *ThroughputReport
+}
+
+type BufferbloatReport struct {
+	Start    time.Time
+	Idle     LatencySummary
+	Download *LoadedLatencyReport
+	Upload   *LoadedLatencyReport
+	Grade    string
+	Error    string
+}
+
+// STUNReport is the result of a binding request to a single STUN server
+type STUNReport struct {
+	Server  string
+	Address string
+	Mapped  string
+	Other   string
+	RTT     time.Duration
+	Error   string
+}
+
+// NATReport classifies the NAT between gomo and the internet using STUN
+type NATReport struct {
+	LocalAddress  string
+	MappedAddress string
+	Mapping       string
+	Filtering     string
+	PortPreserved bool
+	Type          string
+	Console       string
+	Servers       []*STUNReport
+	Error         string
 }
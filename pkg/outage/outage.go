@@ -0,0 +1,408 @@
+// package outage classifies connectivity from the trashcan's view of the WAN
+// and internet reachability probes and keeps a persistent log of outages
+package outage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/store"
+)
+
+const (
+	// StateUp means the gateway answered, reported the WAN connected and the
+	// internet was reachable
+	StateUp = "up"
+	// StateGatewayUnreachable means the trashcan itself could not be scraped
+	StateGatewayUnreachable = "gateway_unreachable"
+	// StateWANDown means the trashcan answered but reported no WAN connection
+	StateWANDown = "wan_down"
+	// StateInternetUnreachable means the WAN was connected but every
+	// reachability probe failed
+	StateInternetUnreachable = "internet_unreachable"
+
+	// ConnectionStatusConnected is the ConnectionStatus the trashcan reports
+	// while attached to the WAN
+	ConnectionStatusConnected = 1
+)
+
+var (
+	// States lists every classification in order of precedence
+	States = []string{StateUp, StateGatewayUnreachable, StateWANDown, StateInternetUnreachable}
+
+	// DefaultThreshold is how many consecutive scrapes must agree before a
+	// state change is recorded
+	DefaultThreshold = 2
+
+	// HeartbeatInterval is how often a running daemon saves the log while up,
+	// so LastSeen marks when monitoring stopped if gomo exits uncleanly
+	HeartbeatInterval = time.Minute
+)
+
+// Outage is a single period of lost connectivity
+type Outage struct {
+	Classification string     `json:"classification"`
+	Reason         string     `json:"reason,omitempty"`
+	Start          time.Time  `json:"start"`
+	End            *time.Time `json:"end,omitempty"`
+	LastSeen       time.Time  `json:"last_seen"`
+}
+
+// Ongoing returns true if the outage has not ended
+func (o *Outage) Ongoing() bool {
+	return o.End == nil
+}
+
+// Duration returns how long the outage lasted, or has lasted so far as of now
+func (o *Outage) Duration(now time.Time) time.Duration {
+	if o.End != nil {
+		return o.End.Sub(o.Start)
+	}
+	return now.Sub(o.Start)
+}
+
+// Gap is a period gomo was not running, which counts as neither uptime nor
+// downtime
+type Gap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Transition describes a recorded change of state. Closed is the outage that
+// ended and Opened the outage that began, either may be nil
+type Transition struct {
+	From   string
+	To     string
+	Opened *Outage
+	Closed *Outage
+}
+
+// Detector is a state machine fed by gateway scrapes and internet probes which
+// records every confirmed outage
+type Detector struct {
+	mu        sync.Mutex
+	path      string
+	Threshold int `json:"-"`
+
+	Since    time.Time `json:"since"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+	Outages  []*Outage `json:"outages"`
+	Gaps     []*Gap    `json:"gaps,omitempty"`
+
+	observed       bool
+	gatewayErr     string
+	wanUp          bool
+	internetKnown  bool
+	internetUp     bool
+	candidate      string
+	candidateStart time.Time
+	candidateCount int
+}
+
+// NewDetector returns an empty Detector which persists to path
+func NewDetector(path string) *Detector {
+	return &Detector{
+		path:      path,
+		Threshold: DefaultThreshold,
+		Outages:   make([]*Outage, 0),
+	}
+}
+
+// Load reads a Detector's outage log from path. A missing file returns an
+// empty Detector. An outage left open by a previous run is closed at the last
+// time it was seen as nothing is known about the time gomo was not running,
+// that time is recorded as a Gap once the next run observes the gateway
+func Load(path string) (*Detector, error) {
+	d := NewDetector(path)
+
+	if _, err := store.LoadJSON(path, d); err != nil {
+		return nil, err
+	}
+
+	if d.Outages == nil {
+		d.Outages = make([]*Outage, 0)
+	}
+	if o := d.current(); o != nil {
+		end := o.LastSeen
+		o.End = &end
+	}
+
+	return d, nil
+}
+
+// Save writes the outage log to its path
+func (d *Detector) Save() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return store.SaveJSON(d.path, d)
+}
+
+// ObserveInternet records the outcome of the latest reachability probes. It
+// is taken into account on the next gateway observation
+func (d *Detector) ObserveInternet(report *models.StatusReport) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.internetUp, d.internetKnown = InternetReachable(report)
+}
+
+// ObserveGateway records the outcome of a trashcan scrape and re-evaluates the
+// state, returning a Transition if a state change was confirmed
+func (d *Detector) ObserveGateway(ret *models.FastmileReturn, at time.Time) *Transition {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.gatewayErr = ""
+	d.wanUp = false
+	switch {
+	case ret.Error != nil:
+		d.gatewayErr = ret.Error.Error()
+	case ret.Body == nil:
+		d.gatewayErr = "empty response"
+	default:
+		for _, c := range ret.Body.ConnectionStatus {
+			if c != nil && c.ConnectionStatus == ConnectionStatusConnected {
+				d.wanUp = true
+			}
+		}
+	}
+
+	if !d.observed {
+		d.observed = true
+		if d.Since.IsZero() {
+			d.Since = at
+		}
+		if !d.LastSeen.IsZero() && at.After(d.LastSeen) {
+			d.Gaps = append(d.Gaps, &Gap{Start: d.LastSeen, End: at})
+		}
+	}
+	d.LastSeen = at
+
+	return d.evaluate(at)
+}
+
+// State returns the current recorded state
+func (d *Detector) State() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if o := d.current(); o != nil {
+		return o.Classification
+	}
+	return StateUp
+}
+
+// Current returns a copy of the ongoing outage, or nil if there is none
+func (d *Detector) Current() *Outage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	o := d.current()
+	if o == nil {
+		return nil
+	}
+	c := *o
+	return &c
+}
+
+// List returns a copy of every recorded outage, most recent first
+func (d *Detector) List() []*Outage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	list := make([]*Outage, 0, len(d.Outages))
+	for _, o := range d.Outages {
+		c := *o
+		list = append(list, &c)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Start.After(list[j].Start)
+	})
+
+	return list
+}
+
+// Summary totals outages that overlap the window from since until now.
+// Uptime is the share of the Monitored time that was not Downtime
+type Summary struct {
+	Window           time.Duration
+	Monitored        time.Duration
+	Unmonitored      time.Duration
+	Count            int
+	Downtime         time.Duration
+	Uptime           float64
+	ByClassification map[string]int
+}
+
+// Summarize totals the outages overlapping the window from since until now.
+// The window is clipped to when monitoring began, and the gaps between runs
+// and any time since the gateway was last seen are left out as unmonitored
+func (d *Detector) Summarize(since time.Time, now time.Time) Summary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if since.Before(d.Since) {
+		since = d.Since
+	}
+
+	summary := Summary{
+		Window:           now.Sub(since),
+		Uptime:           1,
+		ByClassification: make(map[string]int),
+	}
+
+	// monitoring ends at the last observation, not when the log is read
+	until := now
+	if !d.LastSeen.IsZero() && d.LastSeen.Before(now) {
+		until = d.LastSeen
+	}
+	gaps := append([]*Gap{}, d.Gaps...)
+	gaps = append(gaps, &Gap{Start: until, End: now})
+	for _, g := range gaps {
+		start, end := g.Start, g.End
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(now) {
+			end = now
+		}
+		if end.After(start) {
+			summary.Unmonitored += end.Sub(start)
+		}
+	}
+	summary.Monitored = summary.Window - summary.Unmonitored
+
+	for _, o := range d.Outages {
+		start, end := o.Start, until
+		if o.End != nil {
+			end = *o.End
+		}
+		if end.Before(since) || start.After(now) {
+			continue
+		}
+		if start.Before(since) {
+			start = since
+		}
+
+		summary.Count++
+		summary.ByClassification[o.Classification]++
+		summary.Downtime += end.Sub(start)
+	}
+
+	if summary.Monitored > 0 {
+		summary.Uptime = 1 - summary.Downtime.Seconds()/summary.Monitored.Seconds()
+		if summary.Uptime < 0 {
+			summary.Uptime = 0
+		}
+	}
+
+	return summary
+}
+
+// classify returns the state implied by the latest observations, most
+// fundamental failure first
+func (d *Detector) classify() (string, string) {
+	switch {
+	case d.gatewayErr != "":
+		return StateGatewayUnreachable, d.gatewayErr
+	case !d.wanUp:
+		return StateWANDown, "trashcan reports WAN disconnected"
+	case d.internetKnown && !d.internetUp:
+		return StateInternetUnreachable, "all reachability probes failed"
+	default:
+		return StateUp, ""
+	}
+}
+
+func (d *Detector) evaluate(at time.Time) *Transition {
+	state, reason := d.classify()
+
+	if state == d.candidate {
+		d.candidateCount++
+	} else {
+		d.candidate = state
+		d.candidateStart = at
+		d.candidateCount = 1
+	}
+
+	current := d.current()
+	if current != nil {
+		current.LastSeen = at
+	}
+
+	from := StateUp
+	if current != nil {
+		from = current.Classification
+	}
+
+	threshold := d.Threshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	if state == from || d.candidateCount < threshold {
+		return nil
+	}
+
+	// backdate the change to the first observation that agreed with it
+	t := &Transition{From: from, To: state}
+	if current != nil {
+		end := d.candidateStart
+		current.End = &end
+		t.Closed = current
+	}
+	if state != StateUp {
+		t.Opened = &Outage{
+			Classification: state,
+			Reason:         reason,
+			Start:          d.candidateStart,
+			LastSeen:       at,
+		}
+		d.Outages = append(d.Outages, t.Opened)
+	}
+
+	return t
+}
+
+// current returns the ongoing outage if there is one
+func (d *Detector) current() *Outage {
+	if len(d.Outages) == 0 {
+		return nil
+	}
+	if o := d.Outages[len(d.Outages)-1]; o.Ongoing() {
+		return o
+	}
+	return nil
+}
+
+// InternetReachable returns whether any reachability probe in report
+// succeeded, and false for known if report contains no probes
+func InternetReachable(report *models.StatusReport) (reachable bool, known bool) {
+	if report == nil {
+		return false, false
+	}
+
+	for _, p := range report.Pings {
+		known = true
+		if p.Error == nil && p.Body != nil && p.Body.PacketsRecv > 0 {
+			return true, true
+		}
+	}
+	for _, t := range report.TCP {
+		known = true
+		if t.Up {
+			return true, true
+		}
+	}
+	for _, h := range report.HTTP {
+		known = true
+		if h.StatusCode > 0 {
+			return true, true
+		}
+	}
+
+	return false, known
+}
@@ -0,0 +1,154 @@
+package outage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func fakeReturn(connected bool) *models.FastmileReturn {
+	status := 0
+	if connected {
+		status = ConnectionStatusConnected
+	}
+	return &models.FastmileReturn{
+		Body: &models.FastmileRadioStatus{
+			ConnectionStatus: []*models.ConnectionStatus{{ConnectionStatus: status}},
+		},
+	}
+}
+
+func fakeReport(up bool) *models.StatusReport {
+	return &models.StatusReport{
+		TCP: []*models.TCPReport{{Target: "example.com:443", Up: up}},
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name     string
+		ret      *models.FastmileReturn
+		internet *models.StatusReport
+		expected string
+	}{
+		{"up", fakeReturn(true), fakeReport(true), StateUp},
+		{"no probes", fakeReturn(true), nil, StateUp},
+		{"gateway", &models.FastmileReturn{Error: errors.New("timeout")}, fakeReport(false), StateGatewayUnreachable},
+		{"wan", fakeReturn(false), fakeReport(false), StateWANDown},
+		{"internet", fakeReturn(true), fakeReport(false), StateInternetUnreachable},
+	}
+
+	for _, c := range cases {
+		d := NewDetector(filepath.Join(t.TempDir(), "outages.json"))
+		d.Threshold = 1
+		d.ObserveInternet(c.internet)
+		d.ObserveGateway(c.ret, time.Now())
+
+		if d.State() != c.expected {
+			t.Fatalf("%s: expected state %s but got %s", c.name, c.expected, d.State())
+		}
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	d := NewDetector(filepath.Join(t.TempDir(), "outages.json"))
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * 15 * time.Second) }
+
+	d.ObserveGateway(fakeReturn(true), at(0))
+	// a single blip is not an outage
+	if tr := d.ObserveGateway(fakeReturn(false), at(1)); tr != nil {
+		t.Fatalf("Expected no transition after one failed scrape but got %+v", tr)
+	}
+	d.ObserveGateway(fakeReturn(true), at(2))
+
+	d.ObserveGateway(fakeReturn(false), at(3))
+	tr := d.ObserveGateway(fakeReturn(false), at(4))
+	if tr == nil || tr.Opened == nil || tr.To != StateWANDown {
+		t.Fatalf("Expected a wan_down outage to open but got %+v", tr)
+	}
+	if !tr.Opened.Start.Equal(at(3)) {
+		t.Fatalf("Expected outage to be backdated to %s but got %s", at(3), tr.Opened.Start)
+	}
+
+	d.ObserveGateway(fakeReturn(true), at(5))
+	tr = d.ObserveGateway(fakeReturn(true), at(6))
+	if tr == nil || tr.Closed == nil || tr.To != StateUp {
+		t.Fatalf("Expected outage to close but got %+v", tr)
+	}
+	if tr.Closed.Duration(at(6)) != 30*time.Second {
+		t.Fatalf("Expected a 30s outage but got %s", tr.Closed.Duration(at(6)))
+	}
+
+	d.ObserveGateway(fakeReturn(true), at(7))
+	d.ObserveGateway(fakeReturn(true), at(8))
+	summary := d.Summarize(start, at(8))
+	if summary.Count != 1 || summary.Downtime != 30*time.Second {
+		t.Fatalf("Expected one 30s outage but got %+v", summary)
+	}
+	if summary.Uptime != 0.75 {
+		t.Fatalf("Expected 75%% uptime but got %f", summary.Uptime)
+	}
+}
+
+func TestLoadClosesOngoing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outages.json")
+	d := NewDetector(path)
+	d.Threshold = 1
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d.ObserveGateway(&models.FastmileReturn{Error: errors.New("timeout")}, start)
+	d.ObserveGateway(&models.FastmileReturn{Error: errors.New("timeout")}, start.Add(time.Minute))
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.State() != StateUp {
+		t.Fatalf("Expected loaded outage to be closed but state is %s", loaded.State())
+	}
+	if o := loaded.List()[0]; o.Duration(time.Now()) != time.Minute {
+		t.Fatalf("Expected outage closed at last seen after 1m but got %s", o.Duration(time.Now()))
+	}
+}
+
+func TestUnmonitored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outages.json")
+	d := NewDetector(path)
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d.ObserveGateway(fakeReturn(true), start)
+	d.ObserveGateway(fakeReturn(true), start.Add(time.Hour))
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// gomo was down for an hour before the next run
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.Threshold = 1
+	loaded.ObserveGateway(fakeReturn(true), start.Add(2*time.Hour))
+	loaded.ObserveGateway(fakeReturn(false), start.Add(2*time.Hour+30*time.Minute))
+	loaded.ObserveGateway(fakeReturn(true), start.Add(3*time.Hour))
+
+	if len(loaded.Gaps) != 1 || loaded.Gaps[0].End.Sub(loaded.Gaps[0].Start) != time.Hour {
+		t.Fatalf("Expected a 1h gap but got %+v", loaded.Gaps)
+	}
+
+	// and has not been seen for the last hour either
+	summary := loaded.Summarize(start, start.Add(4*time.Hour))
+	if summary.Unmonitored != 2*time.Hour || summary.Monitored != 2*time.Hour {
+		t.Fatalf("Expected 2h monitored and 2h unmonitored but got %+v", summary)
+	}
+	if summary.Downtime != 30*time.Minute || summary.Uptime != 0.75 {
+		t.Fatalf("Expected 30m downtime and 75%% uptime but got %+v", summary)
+	}
+}
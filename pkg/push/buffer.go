@@ -0,0 +1,235 @@
+package push
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+const segmentExt = ".seg"
+
+// Buffer holds the batches not yet sent, oldest first. Batches
+// stay in memory until a send fails, then every pending batch is written to
+// dir so an outage of the monitored link, or a restart during one, loses
+// nothing. Once more than limit bytes are buffered the oldest are dropped
+type Buffer struct {
+	mu       sync.Mutex
+	dir      string
+	limit    int64
+	segments []*segment
+	size     int64
+	samples  int
+	dropped  int64
+	last     int64
+}
+
+// segment is one batch of samples, a snappy compressed remote write request or
+// lines of InfluxDB line protocol
+type segment struct {
+	seq     int64
+	samples int
+	size    int64
+	// data is nil once the segment is written to disk
+	data []byte
+}
+
+// OpenBuffer returns a Buffer keeping at most limit bytes which spills to
+// dir, picking up any batches left there by a previous run
+func OpenBuffer(dir string, limit int64) (*Buffer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Buffer{dir: dir, limit: limit}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+		s := &segment{}
+		if _, err := fmt.Sscanf(strings.TrimSuffix(e.Name(), segmentExt), "%d-%d", &s.seq, &s.samples); err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.size = info.Size()
+		b.segments = append(b.segments, s)
+		b.size += s.size
+		b.samples += s.samples
+		if s.seq > b.last {
+			b.last = s.seq
+		}
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i].seq < b.segments[j].seq })
+	b.evict()
+
+	return b, nil
+}
+
+// Add buffers an encoded request of samples
+func (b *Buffer) Add(request []byte, samples int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	seq := time.Now().UnixNano()
+	if seq <= b.last {
+		seq = b.last + 1
+	}
+	b.last = seq
+
+	data := snappy.Encode(nil, request)
+	b.segments = append(b.segments, &segment{seq: seq, samples: samples, size: int64(len(data)), data: data})
+	b.size += int64(len(data))
+	b.samples += samples
+	b.evict()
+}
+
+// Peek returns the oldest buffered requests merged into one of at most max
+// samples, or the oldest request alone if that is larger, and the sequence
+// of the last one merged to Remove or Drop them by. Requests which can't be
+// read back from disk are dropped
+func (b *Buffer) Peek(max int) ([]byte, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var merged []byte
+	var through int64
+	var n, samples int
+	for n < len(b.segments) {
+		s := b.segments[n]
+		if n > 0 && samples+s.samples > max {
+			break
+		}
+
+		request, err := b.read(s)
+		if err != nil {
+			b.dropped += int64(s.samples)
+			b.remove(n)
+			continue
+		}
+
+		// concatenated remote write requests decode as one with every series
+		// of both, and lines of line protocol are just more lines
+		merged = append(merged, request...)
+		samples += s.samples
+		through = s.seq
+		n++
+	}
+
+	return merged, through
+}
+
+// Remove removes the requests up to and including through once sent
+func (b *Buffer) Remove(through int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.segments) > 0 && b.segments[0].seq <= through {
+		b.remove(0)
+	}
+}
+
+// Drop drops the requests up to and including through unsent, ex. when the
+// endpoint rejected them
+func (b *Buffer) Drop(through int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.segments) > 0 && b.segments[0].seq <= through {
+		b.dropped += int64(b.segments[0].samples)
+		b.remove(0)
+	}
+}
+
+// Spill writes every request still in memory to disk
+func (b *Buffer) Spill() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.segments {
+		if s.data == nil {
+			continue
+		}
+		path := b.path(s)
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, s.data, 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+		s.data = nil
+	}
+
+	return nil
+}
+
+// Len returns the number of buffered requests
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.segments)
+}
+
+// Samples returns the number of buffered samples
+func (b *Buffer) Samples() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.samples
+}
+
+// Dropped returns the total number of samples dropped unsent
+func (b *Buffer) Dropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// evict drops the oldest requests until the buffer fits its limit, always
+// keeping the newest
+func (b *Buffer) evict() {
+	for b.limit > 0 && b.size > b.limit && len(b.segments) > 1 {
+		b.dropped += int64(b.segments[0].samples)
+		b.remove(0)
+	}
+}
+
+func (b *Buffer) remove(i int) {
+	s := b.segments[i]
+	if s.data == nil {
+		// a file left behind is sent again after a restart, which the
+		// receiver rejects as out of order at worst
+		os.Remove(b.path(s))
+	}
+	b.segments = append(b.segments[:i], b.segments[i+1:]...)
+	b.size -= s.size
+	b.samples -= s.samples
+}
+
+func (b *Buffer) read(s *segment) ([]byte, error) {
+	data := s.data
+	if data == nil {
+		var err error
+		data, err = os.ReadFile(b.path(s))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snappy.Decode(nil, data)
+}
+
+func (b *Buffer) path(s *segment) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d-%d%s", s.seq, s.samples, segmentExt))
+}
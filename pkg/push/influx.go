@@ -0,0 +1,230 @@
+package push
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const influxWritePath = "/api/v2/write"
+
+// Point is one sample written to InfluxDB, a measurement with tags and fields
+// taken at Time
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]float64
+	Time        time.Time
+}
+
+// PointWriter is a Sink written samples as they are taken, keeping their own
+// timestamps, rather than gathering metrics on every Push
+type PointWriter interface {
+	Sink
+	Write(points ...Point)
+}
+
+// Influx writes points as line protocol to the InfluxDB v2 write API, in
+// batches of at most BatchSize points. Like RemoteWriter points not yet
+// written are held in a Buffer and written oldest first once InfluxDB is
+// reachable again
+type Influx struct {
+	URL       string
+	Token     string
+	BatchSize int
+	Retries   int
+	Backoff   time.Duration
+	Gzip      bool
+
+	mu     sync.Mutex
+	client *http.Client
+	tags   map[string]string
+	buffer *Buffer
+	stats  Stats
+}
+
+// NewInflux returns an Influx writing to bucket of org on the InfluxDB at
+// base, authenticated with token when set, with tags added to every point.
+// InfluxDB 1.8 takes a database/retention-policy as bucket and no org
+func NewInflux(base string, org string, bucket string, token string, tags map[string]string, buffer *Buffer, client *http.Client) (*Influx, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("an InfluxDB bucket is required")
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, influxWritePath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + influxWritePath
+	}
+	q := u.Query()
+	if org != "" {
+		q.Set("org", org)
+	}
+	q.Set("bucket", bucket)
+	q.Set("precision", "ms")
+	u.RawQuery = q.Encode()
+
+	return &Influx{
+		URL:       u.String(),
+		Token:     token,
+		BatchSize: DefaultBatchSize,
+		Retries:   DefaultRetries,
+		Backoff:   DefaultBackoff,
+		Gzip:      true,
+		client:    client,
+		tags:      tags,
+		buffer:    buffer,
+	}, nil
+}
+
+// Write buffers points until the next Push
+func (i *Influx) Write(points ...Point) {
+	lines, n := EncodeLines(points, i.tags)
+	if n > 0 {
+		i.buffer.Add(lines, n)
+	}
+}
+
+// Push writes the buffered points, at is ignored as every point carries the
+// time it was taken
+func (i *Influx) Push(ctx context.Context, at time.Time) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return drain(ctx, i.buffer, i.BatchSize, i.Retries, i.Backoff, &i.stats, i.send)
+}
+
+// Stats returns the requests sent and failed and the points dropped and still
+// buffered
+func (i *Influx) Stats() Stats {
+	i.mu.Lock()
+	stats := i.stats
+	i.mu.Unlock()
+
+	stats.Dropped = i.buffer.Dropped()
+	stats.Buffered = i.buffer.Samples()
+	return stats
+}
+
+// Close writes any points not yet sent to disk
+func (i *Influx) Close() error {
+	return i.buffer.Spill()
+}
+
+func (i *Influx) send(ctx context.Context, lines []byte) error {
+	body := lines
+	if i.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(lines); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", "gomo")
+	if i.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if i.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.Token)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return &retryable{err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return statusError(resp)
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+)
+
+// EncodeLines encodes points as line protocol with millisecond timestamps,
+// adding tags to every point which doesn't have them already. Tags without a
+// value and fields which aren't finite are left out, as is a point left
+// without fields, and a point without a time is taken now. Returns the lines
+// and how many points they hold
+func EncodeLines(points []Point, tags map[string]string) ([]byte, int) {
+	var buf []byte
+	var n int
+	for _, p := range points {
+		fields := make([]string, 0, len(p.Fields))
+		for k, v := range p.Fields {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				fields = append(fields, k)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		sort.Strings(fields)
+
+		all := make(map[string]string, len(tags)+len(p.Tags))
+		for k, v := range tags {
+			all[k] = v
+		}
+		for k, v := range p.Tags {
+			all[k] = v
+		}
+		keys := make([]string, 0, len(all))
+		for k, v := range all {
+			if v != "" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		buf = append(buf, measurementEscaper.Replace(p.Measurement)...)
+		for _, k := range keys {
+			buf = append(buf, ',')
+			buf = append(buf, keyEscaper.Replace(k)...)
+			buf = append(buf, '=')
+			buf = append(buf, keyEscaper.Replace(all[k])...)
+		}
+		for j, k := range fields {
+			if j == 0 {
+				buf = append(buf, ' ')
+			} else {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, keyEscaper.Replace(k)...)
+			buf = append(buf, '=')
+			buf = strconv.AppendFloat(buf, p.Fields[k], 'g', -1, 64)
+		}
+		at := p.Time
+		if at.IsZero() {
+			at = time.Now()
+		}
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, at.UnixMilli(), 10)
+		buf = append(buf, '\n')
+		n++
+	}
+	return buf, n
+}
@@ -0,0 +1,200 @@
+// package push ships the daemon's metrics to a prometheus Pushgateway, a
+// remote write endpoint or InfluxDB for gateways prometheus can't scrape, ex.
+// behind CGNAT
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	ModePushgateway = "pushgateway"
+	ModeRemoteWrite = "remote-write"
+	ModeInflux      = "influx"
+
+	DefaultJob       = "gomo"
+	DefaultInterval  = 15 * time.Second
+	DefaultBatchSize = 2000
+	// DefaultBufferSize is how many bytes of compressed samples are kept on
+	// disk while the endpoint is unreachable, about a day of every metric
+	DefaultBufferSize = 64 << 20
+	DefaultRetries    = 3
+	DefaultBackoff    = time.Second
+)
+
+var Modes = []string{ModePushgateway, ModeRemoteWrite, ModeInflux}
+
+// Sink ships the metrics of a gatherer somewhere on every Push
+type Sink interface {
+	Push(ctx context.Context, at time.Time) error
+	Stats() Stats
+	// Close persists anything not yet shipped
+	Close() error
+}
+
+// Stats are the running totals of a Sink. Sent and Failed count requests,
+// Dropped and Buffered count samples
+type Stats struct {
+	Sent     int64
+	Failed   int64
+	Dropped  int64
+	Buffered int
+	LastSent time.Time
+}
+
+// retryable marks an error worth trying again, ex. a network error or an
+// overloaded endpoint, as opposed to a request the endpoint rejected
+type retryable struct {
+	err error
+}
+
+func (r *retryable) Error() string { return r.err.Error() }
+func (r *retryable) Unwrap() error { return r.err }
+
+// retry calls f up to retries more times while it fails with a retryable
+// error, doubling the wait from backoff each time. failed is called for every
+// failed attempt
+func retry(ctx context.Context, retries int, backoff time.Duration, failed func(), f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		failed()
+
+		var r *retryable
+		if !errors.As(err, &r) || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
+
+// drain sends the batches in buffer oldest first with send, merged into
+// requests of at most batch samples. Once a batch can't be sent the buffer is
+// written to disk and the remaining batches are left for the next drain, a
+// batch the endpoint rejected is dropped. stats must be guarded by the caller
+func drain(ctx context.Context, buffer *Buffer, batch int, retries int, backoff time.Duration, stats *Stats, send func(context.Context, []byte) error) error {
+	for buffer.Len() > 0 {
+		request, through := buffer.Peek(batch)
+		if request == nil {
+			break
+		}
+
+		err := retry(ctx, retries, backoff, func() { stats.Failed++ }, func() error {
+			return send(ctx, request)
+		})
+
+		var r *retryable
+		switch {
+		case err == nil:
+			buffer.Remove(through)
+			stats.Sent++
+			stats.LastSent = time.Now()
+		case errors.As(err, &r):
+			if spillErr := buffer.Spill(); spillErr != nil {
+				return spillErr
+			}
+			return err
+		default:
+			// resending a request the endpoint rejected would fail forever
+			buffer.Drop(through)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// statusError returns an error for a non 2xx response, retryable for server
+// errors and rate limiting
+func statusError(resp *http.Response) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err := fmt.Errorf("%s returned %s", resp.Request.URL.Redacted(), resp.Status)
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &retryable{err}
+	}
+	return err
+}
+
+// Gateway pushes every metric to a Pushgateway, replacing the previous push
+// of the same job and labels. A Pushgateway only holds the latest values so
+// nothing is buffered, an outage is a gap
+type Gateway struct {
+	Retries int
+	Backoff time.Duration
+
+	mu     sync.Mutex
+	pusher *push.Pusher
+	stats  Stats
+}
+
+// NewGateway returns a Gateway pushing g to the Pushgateway at url grouped by
+// job and labels
+func NewGateway(url string, job string, labels map[string]string, g prometheus.Gatherer, client *http.Client) *Gateway {
+	pusher := push.New(url, job).Gatherer(g).Client(client)
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pusher = pusher.Grouping(name, labels[name])
+	}
+
+	return &Gateway{
+		Retries: DefaultRetries,
+		Backoff: DefaultBackoff,
+		pusher:  pusher,
+	}
+}
+
+// Push gathers and pushes every metric
+func (g *Gateway) Push(ctx context.Context, at time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	err := retry(ctx, g.Retries, g.Backoff, func() { g.stats.Failed++ }, func() error {
+		// the client doesn't tell server errors apart, so every failure is retried
+		if err := g.pusher.PushContext(ctx); err != nil {
+			return &retryable{err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	g.stats.Sent++
+	g.stats.LastSent = at
+	return nil
+}
+
+// Stats returns the pushes made and failed
+func (g *Gateway) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// Close does nothing, a Pushgateway is only ever sent the latest values
+func (g *Gateway) Close() error {
+	return nil
+}
@@ -0,0 +1,222 @@
+package push
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest decodes the samples of a WriteRequest encoded by
+// EncodeWriteRequest, labels keyed by name
+func decodeWriteRequest(t *testing.T, b []byte) []map[string]string {
+	fields := func(b []byte, f func(num protowire.Number, typ protowire.Type, b []byte) int) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatalf("Bad tag: %v", protowire.ParseError(n))
+			}
+			b = b[n:]
+			n = f(num, typ, b)
+			if n < 0 {
+				t.Fatalf("Bad field %d: %v", num, protowire.ParseError(n))
+			}
+			b = b[n:]
+		}
+	}
+
+	var ret []map[string]string
+	fields(b, func(_ protowire.Number, _ protowire.Type, b []byte) int {
+		series, n := protowire.ConsumeBytes(b)
+		s := map[string]string{}
+		fields(series, func(num protowire.Number, _ protowire.Type, b []byte) int {
+			msg, n := protowire.ConsumeBytes(b)
+			if num == 1 {
+				var name string
+				fields(msg, func(num protowire.Number, _ protowire.Type, b []byte) int {
+					v, n := protowire.ConsumeString(b)
+					if num == 1 {
+						name = v
+					} else {
+						s[name] = v
+					}
+					return n
+				})
+				return n
+			}
+			fields(msg, func(num protowire.Number, _ protowire.Type, b []byte) int {
+				if num == 1 {
+					v, n := protowire.ConsumeFixed64(b)
+					s["@value"] = formatFloat(math.Float64frombits(v))
+					return n
+				}
+				v, n := protowire.ConsumeVarint(b)
+				s["@timestamp"] = formatFloat(float64(v))
+				return n
+			})
+			return n
+		})
+		ret = append(ret, s)
+		return n
+	})
+	return ret
+}
+
+func TestRemoteWrite(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "gomo_5g_snr_db"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "gomo_5g_sampled_rsrp_dbm", Buckets: []float64{-105}})
+	reg.MustRegister(gauge, histogram)
+	gauge.Set(12)
+	histogram.Observe(-110)
+
+	var mu sync.Mutex
+	status := http.StatusServiceUnavailable
+	var received [][]map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if status != http.StatusNoContent {
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		request, err := snappy.Decode(nil, body)
+		if err != nil {
+			t.Errorf("Expected a snappy body: %v", err)
+		}
+		received = append(received, decodeWriteRequest(t, request))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	newWriter := func() *RemoteWriter {
+		buffer, err := OpenBuffer(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewRemoteWriter(srv.URL, map[string]string{"job": "gomo", "instance": "cabin"}, reg, buffer, srv.Client())
+		w.Backoff = time.Millisecond
+		w.Retries = 1
+		return w
+	}
+
+	// the endpoint is down, the samples are retried once then spilled to disk
+	w := newWriter()
+	if err := w.Push(context.Background(), time.UnixMilli(1000)); err == nil {
+		t.Fatal("Expected an error pushing to an unavailable endpoint")
+	}
+	if stats := w.Stats(); stats.Failed != 2 || stats.Buffered != 5 {
+		t.Fatalf("Expected 2 failed attempts and 5 buffered samples but got %+v", stats)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("Expected the batch written to disk but found %d files", len(files))
+	}
+
+	// after a restart the buffered samples go out first, merged into one request
+	mu.Lock()
+	status = http.StatusNoContent
+	mu.Unlock()
+	w = newWriter()
+	gauge.Set(9)
+	if err := w.Push(context.Background(), time.UnixMilli(2000)); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || len(received[0]) != 10 {
+		t.Fatalf("Expected one request of 10 samples but got %v", received)
+	}
+	if bucket := received[0][0]; bucket["__name__"] != "gomo_5g_sampled_rsrp_dbm_bucket" || bucket["le"] != "-105" || bucket["@value"] != "1" || bucket["@timestamp"] != "1000" {
+		t.Fatalf("Expected the buffered -105 bucket first but got %v", bucket)
+	}
+	if snr := received[0][4]; snr["__name__"] != "gomo_5g_snr_db" || snr["@value"] != "12" || snr["instance"] != "cabin" || snr["job"] != "gomo" {
+		t.Fatalf("Expected the buffered SNR sample but got %v", snr)
+	}
+	if snr := received[0][9]; snr["@value"] != "9" || snr["@timestamp"] != "2000" {
+		t.Fatalf("Expected the new SNR sample last but got %v", snr)
+	}
+	if stats := w.Stats(); stats.Sent != 1 || stats.Buffered != 0 {
+		t.Fatalf("Expected the buffer sent but got %+v", stats)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("Expected the sent batch removed from disk but found %d files", len(files))
+	}
+
+	// a rejected request is dropped rather than retried forever
+	mu.Lock()
+	status = http.StatusBadRequest
+	mu.Unlock()
+	if err := w.Push(context.Background(), time.UnixMilli(3000)); err == nil {
+		t.Fatal("Expected an error for a rejected request")
+	}
+	if stats := w.Stats(); stats.Dropped != 5 || stats.Buffered != 0 || stats.Failed != 1 {
+		t.Fatalf("Expected the rejected samples dropped after one attempt but got %+v", stats)
+	}
+}
+
+func TestInflux(t *testing.T) {
+	var path, auth, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.RequestURI()
+		auth = r.Header.Get("Authorization")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Expected a gzip body: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(zr)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	buffer, err := OpenBuffer(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewInflux(srv.URL+"/", "home", "gomo", "secret", map[string]string{"gateway": "the cabin"}, buffer, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.UnixMilli(1000)
+	w.Write(Point{
+		Measurement: "gomo_radio",
+		Tags:        map[string]string{"radio": "5g", "band": "n41", "pci": "392"},
+		Fields:      map[string]float64{"snr_db": 12, "rsrp_dbm": -95.5},
+		Time:        at,
+	})
+	w.Write(
+		Point{Measurement: "gomo_ping", Tags: map[string]string{"target": "1.1.1.1", "pci": ""}, Fields: map[string]float64{"up": 0, "rtt_avg_seconds": math.NaN()}, Time: at.Add(time.Second)},
+		Point{Measurement: "gomo_ping", Fields: map[string]float64{"rtt_avg_seconds": math.NaN()}, Time: at.Add(time.Second)},
+	)
+	if stats := w.Stats(); stats.Buffered != 2 {
+		t.Fatalf("Expected the point without fields left out but got %+v", stats)
+	}
+
+	if err := w.Push(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/api/v2/write?bucket=gomo&org=home&precision=ms" || auth != "Token secret" {
+		t.Fatalf("Expected a write to the gomo bucket with the token but got %s %q", path, auth)
+	}
+	expected := "gomo_radio,band=n41,gateway=the\\ cabin,pci=392,radio=5g rsrp_dbm=-95.5,snr_db=12 1000\n" +
+		"gomo_ping,gateway=the\\ cabin,target=1.1.1.1 up=0 2000\n"
+	if body != expected {
+		t.Fatalf("Expected\n%s\nbut got\n%s", expected, body)
+	}
+	if stats := w.Stats(); stats.Sent != 1 || stats.Buffered != 0 {
+		t.Fatalf("Expected both writes sent in one request but got %+v", stats)
+	}
+}
@@ -0,0 +1,247 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Label is a label of a remote write series
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one sample of a remote write series, Timestamp is in unix
+// milliseconds
+type Sample struct {
+	Labels    []Label
+	Value     float64
+	Timestamp int64
+}
+
+// RemoteWriter sends every metric to a prometheus remote write endpoint in
+// batches of at most BatchSize samples. Samples not yet sent are held in a
+// Buffer and sent oldest first once the endpoint is reachable again, so the
+// receiver keeps the history of an outage
+type RemoteWriter struct {
+	URL       string
+	BatchSize int
+	Retries   int
+	Backoff   time.Duration
+
+	mu       sync.Mutex
+	client   *http.Client
+	gatherer prometheus.Gatherer
+	labels   []Label
+	buffer   *Buffer
+	stats    Stats
+}
+
+// NewRemoteWriter returns a RemoteWriter sending g to url with labels added
+// to every series, buffering unsent samples in buffer
+func NewRemoteWriter(url string, labels map[string]string, g prometheus.Gatherer, buffer *Buffer, client *http.Client) *RemoteWriter {
+	extra := make([]Label, 0, len(labels))
+	for name, value := range labels {
+		extra = append(extra, Label{Name: name, Value: value})
+	}
+
+	return &RemoteWriter{
+		URL:       url,
+		BatchSize: DefaultBatchSize,
+		Retries:   DefaultRetries,
+		Backoff:   DefaultBackoff,
+		client:    client,
+		gatherer:  g,
+		labels:    extra,
+		buffer:    buffer,
+	}
+}
+
+// Push gathers every metric as of at into the buffer, then sends the buffer.
+// Once a batch can't be sent the buffer is written to disk and the remaining
+// batches are left for the next Push
+func (w *RemoteWriter) Push(ctx context.Context, at time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// a partial gather is still worth sending
+	families, err := w.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+	samples := Flatten(families, w.labels, at)
+	for len(samples) > 0 {
+		n := w.BatchSize
+		if n <= 0 || n > len(samples) {
+			n = len(samples)
+		}
+		w.buffer.Add(EncodeWriteRequest(samples[:n]), n)
+		samples = samples[n:]
+	}
+
+	return drain(ctx, w.buffer, w.BatchSize, w.Retries, w.Backoff, &w.stats, w.send)
+}
+
+// Stats returns the requests sent and failed and the samples dropped and
+// still buffered
+func (w *RemoteWriter) Stats() Stats {
+	w.mu.Lock()
+	stats := w.stats
+	w.mu.Unlock()
+
+	stats.Dropped = w.buffer.Dropped()
+	stats.Buffered = w.buffer.Samples()
+	return stats
+}
+
+// Close writes any samples not yet sent to disk
+func (w *RemoteWriter) Close() error {
+	return w.buffer.Spill()
+}
+
+func (w *RemoteWriter) send(ctx context.Context, request []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(snappy.Encode(nil, request)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "gomo")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return &retryable{err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return statusError(resp)
+}
+
+// Flatten turns gathered metric families into remote write samples the way
+// prometheus would store them after a scrape at at, histograms and summaries
+// become their _bucket, _sum and _count series. labels are added to every
+// series which doesn't have them already
+func Flatten(families []*dto.MetricFamily, labels []Label, at time.Time) []Sample {
+	var samples []Sample
+
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			ts := at.UnixMilli()
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(suffix string, value float64, extra ...Label) {
+				samples = append(samples, Sample{
+					Labels:    seriesLabels(name+suffix, m.GetLabel(), labels, extra...),
+					Value:     value,
+					Timestamp: ts,
+				})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						continue
+					}
+					add("_bucket", float64(b.GetCumulativeCount()), Label{"le", formatFloat(b.GetUpperBound())})
+				}
+				add("_bucket", float64(h.GetSampleCount()), Label{"le", "+Inf"})
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add("", q.GetValue(), Label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+
+	return samples
+}
+
+// seriesLabels returns the sorted labels of a series
+func seriesLabels(name string, pairs []*dto.LabelPair, labels []Label, extra ...Label) []Label {
+	ret := make([]Label, 0, 1+len(pairs)+len(extra)+len(labels))
+	ret = append(ret, Label{"__name__", name})
+	seen := make(map[string]bool, len(pairs)+len(extra))
+	for _, p := range pairs {
+		ret = append(ret, Label{p.GetName(), p.GetValue()})
+		seen[p.GetName()] = true
+	}
+	for _, l := range extra {
+		ret = append(ret, l)
+		seen[l.Name] = true
+	}
+	for _, l := range labels {
+		if !seen[l.Name] {
+			ret = append(ret, l)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// EncodeWriteRequest encodes samples as a remote write WriteRequest protobuf,
+// one TimeSeries per sample
+func EncodeWriteRequest(samples []Sample) []byte {
+	var buf, series, label, sample []byte
+	for _, s := range samples {
+		series = series[:0]
+		for _, l := range s.Labels {
+			label = label[:0]
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.Name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.Value)
+
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+
+		sample = sample[:0]
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.Timestamp))
+
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, series)
+	}
+	return buf
+}
@@ -0,0 +1,348 @@
+// package slo tracks service level objectives over the status checks and keeps
+// a persistent record of good and total events to compute error budgets from
+package slo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/outage"
+	"github.com/asciifaceman/gomo/pkg/store"
+)
+
+const (
+	// IndicatorLatency counts every ping packet, good if it was answered
+	// under the objective's threshold
+	IndicatorLatency = "latency"
+	// IndicatorLoss counts every ping packet, good if it was answered
+	IndicatorLoss = "loss"
+	// IndicatorAvailability counts every status check, good if any ping,
+	// TCP or HTTP probe reached the internet
+	IndicatorAvailability = "availability"
+
+	// BucketSize is the granularity events are recorded at
+	BucketSize = time.Hour
+)
+
+var (
+	Indicators = []string{IndicatorLatency, IndicatorLoss, IndicatorAvailability}
+
+	DefaultWindow = 30 * 24 * time.Hour
+
+	// BurnWindow is the recent period the burn rate is measured over
+	BurnWindow = time.Hour
+)
+
+// Config is an objective as written in the config file
+type Config struct {
+	Name      string   `mapstructure:"name"`
+	Indicator string   `mapstructure:"indicator"`
+	Objective float64  `mapstructure:"objective"`
+	Threshold string   `mapstructure:"threshold"`
+	Window    string   `mapstructure:"window"`
+	Targets   []string `mapstructure:"targets"`
+}
+
+// Objective is a parsed service level objective
+type Objective struct {
+	Name      string
+	Indicator string
+	Objective float64
+	Threshold time.Duration
+	Window    time.Duration
+	Targets   []string
+}
+
+// Parse validates cfg and returns its Objective. The objective may be given
+// as a ratio (0.995) or a percentage (99.5)
+func Parse(cfg Config) (*Objective, error) {
+	o := &Objective{
+		Name:      cfg.Name,
+		Indicator: cfg.Indicator,
+		Objective: cfg.Objective,
+		Window:    DefaultWindow,
+		Targets:   cfg.Targets,
+	}
+
+	if o.Name == "" {
+		return nil, fmt.Errorf("SLO without a name")
+	}
+
+	switch o.Indicator {
+	case IndicatorLatency, IndicatorLoss, IndicatorAvailability:
+	default:
+		return nil, fmt.Errorf("SLO %s: unknown indicator %q, expected one of %s", o.Name, o.Indicator, strings.Join(Indicators, ", "))
+	}
+
+	if o.Objective > 1 {
+		o.Objective /= 100
+	}
+	if o.Objective <= 0 || o.Objective >= 1 {
+		return nil, fmt.Errorf("SLO %s: objective must be between 0 and 100%%", o.Name)
+	}
+
+	if o.Indicator == IndicatorLatency {
+		if cfg.Threshold == "" {
+			return nil, fmt.Errorf("SLO %s: latency objectives need a threshold", o.Name)
+		}
+		threshold, err := time.ParseDuration(cfg.Threshold)
+		if err != nil {
+			return nil, fmt.Errorf("SLO %s: %w", o.Name, err)
+		}
+		o.Threshold = threshold
+	}
+
+	if cfg.Window != "" {
+		window, err := ParseWindow(cfg.Window)
+		if err != nil {
+			return nil, fmt.Errorf("SLO %s: %w", o.Name, err)
+		}
+		if window < BucketSize {
+			return nil, fmt.Errorf("SLO %s: window must be at least %s", o.Name, BucketSize)
+		}
+		o.Window = window
+	}
+
+	return o, nil
+}
+
+// ParseWindow parses a duration which may also be given in days, ex. 30d
+func ParseWindow(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// String describes the objective, ex. 99% of pings under 80ms over 30d
+func (o *Objective) String() string {
+	var what string
+	switch o.Indicator {
+	case IndicatorLatency:
+		what = fmt.Sprintf("of pings under %s", o.Threshold)
+	case IndicatorLoss:
+		what = "of pings answered"
+	case IndicatorAvailability:
+		what = "availability"
+	}
+
+	return fmt.Sprintf("%s%% %s over %s", strconv.FormatFloat(o.Objective*100, 'f', -1, 64), what, windowString(o.Window))
+}
+
+// Events counts the good and total events of the objective's indicator in
+// report. Targets without an address in the family they were probed over are
+// left out
+func (o *Objective) Events(report *models.StatusReport) (int64, int64) {
+	var good, total int64
+
+	switch o.Indicator {
+	case IndicatorLatency, IndicatorLoss:
+		for _, p := range report.Pings {
+			if p.NoRecord || p.Body == nil || !o.targeted(p.Hostname) {
+				continue
+			}
+			total += int64(p.Body.PacketsSent)
+			if o.Indicator == IndicatorLoss {
+				good += int64(p.Body.PacketsRecv)
+				continue
+			}
+			for _, rtt := range p.Body.Rtts {
+				if rtt <= o.Threshold {
+					good++
+				}
+			}
+		}
+
+	case IndicatorAvailability:
+		if reachable, known := outage.InternetReachable(report); known {
+			total = 1
+			if reachable {
+				good = 1
+			}
+		}
+	}
+
+	return good, total
+}
+
+func (o *Objective) targeted(target string) bool {
+	if len(o.Targets) == 0 {
+		return true
+	}
+	for _, t := range o.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Bucket is the good and total events of an objective within one BucketSize
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Good  int64     `json:"good"`
+	Total int64     `json:"total"`
+}
+
+// Tracker records the events of each objective and persists them so error
+// budgets survive restarts. Events are kept for as long as each objective's
+// window, Since is when each objective first recorded any
+type Tracker struct {
+	mu         sync.Mutex
+	path       string
+	Objectives []*Objective `json:"-"`
+
+	Since   map[string]time.Time `json:"since"`
+	Buckets map[string][]*Bucket `json:"buckets"`
+}
+
+// NewTracker returns an empty Tracker for objectives which persists to path
+func NewTracker(path string, objectives []*Objective) *Tracker {
+	return &Tracker{
+		path:       path,
+		Objectives: objectives,
+		Since:      make(map[string]time.Time),
+		Buckets:    make(map[string][]*Bucket),
+	}
+}
+
+// Load reads the recorded events of objectives from path. A missing file
+// returns an empty Tracker
+func Load(path string, objectives []*Objective) (*Tracker, error) {
+	t := NewTracker(path, objectives)
+
+	if _, err := store.LoadJSON(path, t); err != nil {
+		return nil, err
+	}
+	if t.Since == nil {
+		t.Since = make(map[string]time.Time)
+	}
+	if t.Buckets == nil {
+		t.Buckets = make(map[string][]*Bucket)
+	}
+
+	return t, nil
+}
+
+// Save writes the recorded events to the Tracker's path
+func (t *Tracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return store.SaveJSON(t.path, t)
+}
+
+// Observe records the events of every objective in report, and drops events
+// which have fallen out of each objective's window
+func (t *Tracker) Observe(report *models.StatusReport, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := at.Truncate(BucketSize)
+
+	for _, o := range t.Objectives {
+		good, total := o.Events(report)
+
+		buckets := t.Buckets[o.Name]
+		if total > 0 {
+			if _, ok := t.Since[o.Name]; !ok {
+				t.Since[o.Name] = at
+			}
+			if n := len(buckets); n > 0 && buckets[n-1].Start.Equal(start) {
+				buckets[n-1].Good += good
+				buckets[n-1].Total += total
+			} else {
+				buckets = append(buckets, &Bucket{Start: start, Good: good, Total: total})
+			}
+		}
+
+		cutoff := at.Add(-o.Window)
+		kept := buckets[:0]
+		for _, b := range buckets {
+			if !b.Start.Add(BucketSize).Before(cutoff) {
+				kept = append(kept, b)
+			}
+		}
+		t.Buckets[o.Name] = kept
+	}
+}
+
+// Status is the compliance and error budget of an objective over its window
+type Status struct {
+	Objective *Objective
+	// Covered is how much of the window has recorded events
+	Covered         time.Duration
+	Good            int64
+	Total           int64
+	Compliance      float64
+	BudgetRemaining float64
+	BurnRate        float64
+	Met             bool
+}
+
+// Report returns the Status of every objective as of now in configured order
+func (t *Tracker) Report(now time.Time) []*Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]*Status, 0, len(t.Objectives))
+	for _, o := range t.Objectives {
+		s := &Status{Objective: o, Compliance: 1, BudgetRemaining: 1, Met: true}
+
+		buckets := make([]*Bucket, len(t.Buckets[o.Name]))
+		copy(buckets, t.Buckets[o.Name])
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+
+		cutoff := now.Add(-o.Window)
+		var recentGood, recentTotal int64
+		for _, b := range buckets {
+			if b.Start.Add(BucketSize).Before(cutoff) {
+				continue
+			}
+			s.Good += b.Good
+			s.Total += b.Total
+			if !b.Start.Add(BucketSize).Before(now.Add(-BurnWindow)) {
+				recentGood += b.Good
+				recentTotal += b.Total
+			}
+		}
+
+		allowed := 1 - o.Objective
+		if s.Total > 0 {
+			first, ok := t.Since[o.Name]
+			if !ok {
+				first = buckets[0].Start
+			}
+			if first.Before(cutoff) {
+				first = cutoff
+			}
+			s.Covered = now.Sub(first)
+			s.Compliance = float64(s.Good) / float64(s.Total)
+			s.BudgetRemaining = 1 - (1-s.Compliance)/allowed
+			s.Met = s.Compliance >= o.Objective
+		}
+		if recentTotal > 0 {
+			s.BurnRate = (1 - float64(recentGood)/float64(recentTotal)) / allowed
+		}
+
+		statuses = append(statuses, s)
+	}
+
+	return statuses
+}
+
+func windowString(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
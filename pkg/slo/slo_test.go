@@ -0,0 +1,81 @@
+package slo
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func TestParse(t *testing.T) {
+	o, err := Parse(Config{Name: "fast", Indicator: IndicatorLatency, Objective: 99, Threshold: "80ms", Window: "7d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Objective != 0.99 || o.Threshold != 80*time.Millisecond || o.Window != 7*24*time.Hour {
+		t.Fatalf("Expected 0.99 under 80ms over 7 days but got %+v", o)
+	}
+	if o.String() != "99% of pings under 80ms over 7d" {
+		t.Fatalf("Unexpected description %q", o.String())
+	}
+
+	for _, cfg := range []Config{
+		{Name: "a", Indicator: "jitter", Objective: 0.99},
+		{Name: "a", Indicator: IndicatorLatency, Objective: 0.99},
+		{Name: "a", Indicator: IndicatorAvailability, Objective: 100},
+		{Name: "a", Indicator: IndicatorAvailability, Objective: 0.99, Window: "30m"},
+	} {
+		if _, err := Parse(cfg); err == nil {
+			t.Fatalf("Expected an error parsing %+v", cfg)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	latency, _ := Parse(Config{Name: "latency", Indicator: IndicatorLatency, Objective: 0.9, Threshold: "50ms", Window: "1d"})
+	path := filepath.Join(t.TempDir(), "slo.json")
+	tracker := NewTracker(path, []*Objective{latency})
+
+	report := &models.StatusReport{
+		Pings: []*models.PingReportReturn{
+			{Hostname: "a", Body: &models.PingReport{
+				PacketsSent: 10,
+				PacketsRecv: 9,
+				Rtts:        []time.Duration{10, 20, 30, 40, 60 * time.Millisecond, 10, 10, 10, 10},
+			}},
+			{Hostname: "b", NoRecord: true},
+		},
+	}
+
+	now := time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC)
+	tracker.Observe(report, now.Add(-25*time.Hour))
+	tracker.Observe(report, now)
+
+	// one slow ping and one lost of 10, the day old check is out of the window
+	s := tracker.Report(now)[0]
+	if s.Total != 10 || s.Good != 8 {
+		t.Fatalf("Expected 8 good of 10 but got %d of %d", s.Good, s.Total)
+	}
+	if s.Met {
+		t.Fatalf("Expected 80%% compliance to miss a 90%% objective")
+	}
+	// 20% bad against a 10% budget is twice the budget spent
+	if s.BudgetRemaining > -0.99 || s.BudgetRemaining < -1.01 {
+		t.Fatalf("Expected the budget overspent by 100%% but got %f", s.BudgetRemaining)
+	}
+	if s.BurnRate < 1.99 || s.BurnRate > 2.01 {
+		t.Fatalf("Expected a burn rate of 2 but got %f", s.BurnRate)
+	}
+
+	if err := tracker.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path, []*Objective{latency})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Report(now)[0]; got.Total != 10 || len(loaded.Buckets["latency"]) != 1 {
+		t.Fatalf("Expected the pruned history to survive a reload but got %+v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/status"
+)
+
+// Grades maps the upper bound of latency increase under load to a letter grade,
+// in the style of the common bufferbloat tests
+var Grades = []struct {
+	Below time.Duration
+	Grade string
+}{
+	{5 * time.Millisecond, "A+"},
+	{30 * time.Millisecond, "A"},
+	{60 * time.Millisecond, "B"},
+	{200 * time.Millisecond, "C"},
+	{400 * time.Millisecond, "D"},
+}
+
+// GradeFail is the grade given when latency increases beyond every Grades bound
+const GradeFail = "F"
+
+// Grade returns the letter grade for an increase in latency under load
+func Grade(increase time.Duration) string {
+	for _, g := range Grades {
+		if increase < g.Below {
+			return g.Grade
+		}
+	}
+	return GradeFail
+}
+
+// gradeRank orders grades from best (0) to worst
+func gradeRank(grade string) int {
+	for i, g := range Grades {
+		if g.Grade == grade {
+			return i
+		}
+	}
+	return len(Grades)
+}
+
+// Bufferbloat pings the status ping targets while idle, then again while the link
+// is saturated in each direction, and grades the increase in latency
+func (s *SpeedTest) Bufferbloat(ctx context.Context, pinger *status.Status) *models.BufferbloatReport {
+	report := &models.BufferbloatReport{
+		Start: time.Now(),
+	}
+
+	if pinger == nil || len(pinger.PingHosts) == 0 {
+		report.Error = "bufferbloat needs at least one ping target"
+		return report
+	}
+	// packets lost to the saturated link must not feed the daemon's loss streaks
+	pinger = pinger.LoadPinger()
+
+	idle, err := pingLatency(pinger)
+	if err != nil {
+		report.Error = fmt.Sprintf("idle ping failed: %v", err)
+		return report
+	}
+	report.Idle = idle
+
+	if s.DownloadURL != "" {
+		report.Download = s.loadedLatency(ctx, DirectionDownload, pinger, idle)
+	}
+	if s.UploadURL != "" {
+		report.Upload = s.loadedLatency(ctx, DirectionUpload, pinger, idle)
+	}
+
+	report.Grade = Grade(0)
+	for _, loaded := range []*models.LoadedLatencyReport{report.Download, report.Upload} {
+		if loaded != nil && gradeRank(loaded.Grade) > gradeRank(report.Grade) {
+			report.Grade = loaded.Grade
+		}
+	}
+
+	return report
+}
+
+func (s *SpeedTest) loadedLatency(ctx context.Context, direction string, pinger *status.Status, idle models.LatencySummary) *models.LoadedLatencyReport {
+	report := &models.LoadedLatencyReport{Direction: direction}
+
+	var err error
+	report.Throughput = s.MeasureWhile(ctx, direction, func(context.Context) {
+		report.Latency, err = pingLatency(pinger)
+	})
+
+	if err != nil {
+		report.Grade = GradeFail
+		if report.Throughput.Error == "" {
+			report.Throughput.Error = fmt.Sprintf("loaded ping failed: %v", err)
+		}
+		return report
+	}
+
+	report.Increase = report.Latency.Avg - idle.Avg
+	if report.Increase < 0 {
+		report.Increase = 0
+	}
+	report.Grade = Grade(report.Increase)
+
+	return report
+}
+
+// pingLatency pings every status ping target and summarizes every RTT observed
+func pingLatency(pinger *status.Status) (models.LatencySummary, error) {
+	var rtts []time.Duration
+	var lastErr error
+
+	for _, result := range pinger.Ping() {
+		if result.Error != nil {
+			lastErr = result.Error
+			continue
+		}
+		rtts = append(rtts, result.Body.Rtts...)
+	}
+
+	if len(rtts) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no ping replies received")
+		}
+		return models.LatencySummary{}, lastErr
+	}
+
+	return SummarizeLatency(rtts), nil
+}
@@ -0,0 +1,431 @@
+// package speedtest measures HTTP throughput and latency under load against
+// configurable download and upload endpoints
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/status"
+	"github.com/asciifaceman/gomo/pkg/tmo"
+)
+
+const (
+	DirectionDownload = "download"
+	DirectionUpload   = "upload"
+
+	// UploadChunkSize is the body size of each upload request
+	UploadChunkSize = 25 * 1000 * 1000
+)
+
+var (
+	DefaultDownloadURL     = "https://speed.cloudflare.com/__down?bytes=25000000"
+	DefaultUploadURL       = "https://speed.cloudflare.com/__up"
+	DefaultStreams         = 4
+	DefaultWarmUp          = 2 * time.Second
+	DefaultDuration        = 10 * time.Second
+	DefaultLatencyInterval = 250 * time.Millisecond
+)
+
+// Config configures a speed test
+type Config struct {
+	DownloadURL     string
+	UploadURL       string
+	Streams         int
+	WarmUp          time.Duration
+	Duration        time.Duration
+	LatencyInterval time.Duration
+}
+
+// DefaultConfig returns a Config populated with default values
+func DefaultConfig() Config {
+	return Config{
+		DownloadURL:     DefaultDownloadURL,
+		UploadURL:       DefaultUploadURL,
+		Streams:         DefaultStreams,
+		WarmUp:          DefaultWarmUp,
+		Duration:        DefaultDuration,
+		LatencyInterval: DefaultLatencyInterval,
+	}
+}
+
+// SpeedTest runs throughput tests and tags them with the trashcan's radio state
+type SpeedTest struct {
+	Config
+	Trashcan *tmo.Trashcan
+	client   *http.Client
+}
+
+// New returns a SpeedTest for cfg. trashcan may be nil to skip radio tagging
+func New(cfg Config, trashcan *tmo.Trashcan) (*SpeedTest, error) {
+	if cfg.Streams < 1 {
+		return nil, fmt.Errorf("speed test needs at least one stream")
+	}
+	if cfg.Duration <= 0 {
+		return nil, fmt.Errorf("speed test duration must be positive")
+	}
+	if cfg.LatencyInterval <= 0 {
+		cfg.LatencyInterval = DefaultLatencyInterval
+	}
+
+	s := &SpeedTest{
+		Config:   cfg,
+		Trashcan: trashcan,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConnsPerHost: cfg.Streams,
+				DisableCompression:  true,
+			},
+		},
+	}
+
+	return s, nil
+}
+
+// Run measures idle latency, then download and upload throughput in turn
+func (s *SpeedTest) Run(ctx context.Context) *models.SpeedTestReport {
+	report := &models.SpeedTestReport{
+		Start: time.Now(),
+	}
+
+	s.tagRadio(report)
+
+	latencyTarget := s.latencyTarget()
+	report.IdleLatency = s.idleLatency(ctx, latencyTarget)
+
+	if s.DownloadURL != "" {
+		report.Download = s.Measure(ctx, DirectionDownload, latencyTarget)
+	}
+	if s.UploadURL != "" {
+		report.Upload = s.Measure(ctx, DirectionUpload, latencyTarget)
+	}
+
+	return report
+}
+
+func (s *SpeedTest) tagRadio(report *models.SpeedTestReport) {
+	if s.Trashcan == nil {
+		return
+	}
+
+	radio, err := s.Trashcan.FetchRadioStatus()
+	if err != nil {
+		report.RadioError = err.Error()
+		return
+	}
+
+	if len(radio.Cell5GStats) > 0 && radio.Cell5GStats[0] != nil && radio.Cell5GStats[0].Stat != nil {
+		stat := radio.Cell5GStats[0].Stat
+		report.Radio5G = models.RadioTag{Band: stat.Band, PCI: stat.PhysicalCellID, SNR: stat.SNRCurrent, RSRP: stat.RSRPCurrent}
+	}
+	if len(radio.CellLTEStats) > 0 && radio.CellLTEStats[0] != nil && radio.CellLTEStats[0].Stat != nil {
+		stat := radio.CellLTEStats[0].Stat
+		report.RadioLTE = models.RadioTag{Band: stat.Band, PCI: stat.PhysicalCellID, SNR: stat.SNRCurrent, RSRP: stat.RSRPCurrent}
+	}
+}
+
+// latencyTarget returns the host:port latency is sampled against, the download
+// endpoint if configured else the upload endpoint
+func (s *SpeedTest) latencyTarget() string {
+	raw := s.DownloadURL
+	if raw == "" {
+		raw = s.UploadURL
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func (s *SpeedTest) idleLatency(ctx context.Context, target string) models.LatencySummary {
+	if target == "" {
+		return models.LatencySummary{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*s.LatencyInterval+5*time.Second)
+	defer cancel()
+
+	samples := make([]time.Duration, 0, 5)
+	for i := 0; i < 5; i++ {
+		if rtt, err := connectLatency(ctx, target); err == nil {
+			samples = append(samples, rtt)
+		}
+		select {
+		case <-ctx.Done():
+			return SummarizeLatency(samples)
+		case <-time.After(s.LatencyInterval):
+		}
+	}
+
+	return SummarizeLatency(samples)
+}
+
+// Measure saturates the link in direction with Streams parallel streams, discards
+// the warm up period and reports throughput and latency over Duration
+func (s *SpeedTest) Measure(ctx context.Context, direction string, latencyTarget string) *models.ThroughputReport {
+	var samples []time.Duration
+
+	report := s.MeasureWhile(ctx, direction, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, s.Duration)
+		defer cancel()
+		samples = sampleLatency(ctx, latencyTarget, s.LatencyInterval)
+	})
+	report.Latency = SummarizeLatency(samples)
+
+	return report
+}
+
+// MeasureWhile saturates the link in direction with Streams parallel streams and
+// once the warm up period has passed reports the throughput achieved while during runs
+func (s *SpeedTest) MeasureWhile(ctx context.Context, direction string, during func(ctx context.Context)) *models.ThroughputReport {
+	report := &models.ThroughputReport{Direction: direction}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	counters := make([]int64, s.Streams)
+	errs := make(chan error, s.Streams)
+	var wg sync.WaitGroup
+
+	for i := 0; i < s.Streams; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := s.Load(ctx, direction, &counters[i]); err != nil && ctx.Err() == nil {
+				errs <- err
+			}
+		}(i)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(s.WarmUp):
+	}
+
+	before := snapshot(counters)
+	start := time.Now()
+
+	if ctx.Err() == nil {
+		during(ctx)
+	}
+
+	after := snapshot(counters)
+	report.Duration = time.Since(start)
+
+	cancel()
+	wg.Wait()
+	close(errs)
+
+	for i := range counters {
+		delta := after[i] - before[i]
+		report.Bytes += delta
+		report.Streams = append(report.Streams, bitsPerSecond(delta, report.Duration))
+	}
+	report.BitsPerSecond = bitsPerSecond(report.Bytes, report.Duration)
+	report.StreamStdDev = StdDev(report.Streams)
+
+	for err := range errs {
+		report.Error = err.Error()
+	}
+
+	return report
+}
+
+// Load keeps a single stream of requests running in direction until ctx is
+// done, adding every byte transferred to counter
+func (s *SpeedTest) Load(ctx context.Context, direction string, counter *int64) error {
+	for ctx.Err() == nil {
+		var err error
+		switch direction {
+		case DirectionDownload:
+			err = s.download(ctx, counter)
+		case DirectionUpload:
+			err = s.upload(ctx, counter)
+		default:
+			return fmt.Errorf("unknown direction %q", direction)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SpeedTest) download(ctx context.Context, counter *int64) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.DownloadURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download returned %s", resp.Status)
+	}
+
+	_, err = io.Copy(io.Discard, &countingReader{r: resp.Body, counter: counter})
+	return err
+}
+
+func (s *SpeedTest) upload(ctx context.Context, counter *int64) error {
+	body := &countingReader{r: io.LimitReader(zeroReader{}, UploadChunkSize), counter: counter}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.UploadURL, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = UploadChunkSize
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("upload returned %s", resp.Status)
+	}
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// sampleLatency measures TCP connect time to target every interval until ctx is done
+func sampleLatency(ctx context.Context, target string, interval time.Duration) []time.Duration {
+	samples := make([]time.Duration, 0)
+	if target == "" {
+		<-ctx.Done()
+		return samples
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return samples
+		case <-ticker.C:
+			if rtt, err := connectLatency(ctx, target); err == nil {
+				samples = append(samples, rtt)
+			}
+		}
+	}
+}
+
+func connectLatency(ctx context.Context, target string) (time.Duration, error) {
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return rtt, nil
+}
+
+// SummarizeLatency reduces latency samples to min/avg/max and jitter
+func SummarizeLatency(samples []time.Duration) models.LatencySummary {
+	summary := models.LatencySummary{Samples: len(samples)}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	var total time.Duration
+	summary.Min = samples[0]
+	for _, sample := range samples {
+		total += sample
+		if sample < summary.Min {
+			summary.Min = sample
+		}
+		if sample > summary.Max {
+			summary.Max = sample
+		}
+	}
+	summary.Avg = total / time.Duration(len(samples))
+	summary.Jitter = status.Jitter(samples)
+
+	return summary
+}
+
+// StdDev returns the population standard deviation of vals
+func StdDev(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range vals {
+		mean += v
+	}
+	mean /= float64(len(vals))
+
+	var variance float64
+	for _, v := range vals {
+		variance += (v - mean) * (v - mean)
+	}
+
+	return math.Sqrt(variance / float64(len(vals)))
+}
+
+func bitsPerSecond(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / d.Seconds()
+}
+
+func snapshot(counters []int64) []int64 {
+	ret := make([]int64, len(counters))
+	for i := range counters {
+		ret[i] = atomic.LoadInt64(&counters[i])
+	}
+	return ret
+}
+
+type countingReader struct {
+	r       io.Reader
+	counter *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(c.counter, int64(n))
+	return n, err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,89 @@
+package speedtest
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStdDev(t *testing.T) {
+	if sd := StdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9}); math.Abs(sd-2) > 1e-9 {
+		t.Fatalf("Expected standard deviation of 2 but got %f", sd)
+	}
+}
+
+func TestRun(t *testing.T) {
+	chunk := make([]byte, 64*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			for i := 0; i < 16; i++ {
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+			}
+		case "POST":
+			io.Copy(io.Discard, r.Body)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.DownloadURL = srv.URL + "/down"
+	cfg.UploadURL = srv.URL + "/up"
+	cfg.Streams = 2
+	cfg.WarmUp = 50 * time.Millisecond
+	cfg.Duration = 200 * time.Millisecond
+	cfg.LatencyInterval = 20 * time.Millisecond
+
+	s, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to create speed test: %v", err)
+	}
+
+	report := s.Run(context.Background())
+	for _, r := range []struct {
+		name string
+		bps  float64
+		n    int
+		err  string
+	}{
+		{"download", report.Download.BitsPerSecond, len(report.Download.Streams), report.Download.Error},
+		{"upload", report.Upload.BitsPerSecond, len(report.Upload.Streams), report.Upload.Error},
+	} {
+		if r.err != "" {
+			t.Fatalf("Expected %s to succeed but got %s", r.name, r.err)
+		}
+		if r.bps <= 0 {
+			t.Fatalf("Expected %s throughput to be measured", r.name)
+		}
+		if r.n != 2 {
+			t.Fatalf("Expected 2 %s streams but got %d", r.name, r.n)
+		}
+	}
+
+	if report.Download.Latency.Samples == 0 {
+		t.Fatalf("Expected latency under load to be sampled")
+	}
+}
+
+func TestGrade(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                      "A+",
+		20 * time.Millisecond:  "A",
+		45 * time.Millisecond:  "B",
+		150 * time.Millisecond: "C",
+		399 * time.Millisecond: "D",
+		2 * time.Second:        "F",
+	}
+
+	for increase, expected := range tests {
+		if g := Grade(increase); g != expected {
+			t.Fatalf("Expected grade %s for %s but got %s", expected, increase, g)
+		}
+	}
+}
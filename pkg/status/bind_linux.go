@@ -0,0 +1,22 @@
+//go:build linux
+
+package status
+
+import (
+	"syscall"
+)
+
+// bindToDevice returns a dialer Control function which pins the socket to the
+// named interface with SO_BINDTODEVICE, so it leaves through that interface
+// regardless of the routing table. This needs CAP_NET_RAW
+func bindToDevice(device string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = syscall.BindToDevice(int(fd), device)
+		}); err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
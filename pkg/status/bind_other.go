@@ -0,0 +1,13 @@
+//go:build !linux
+
+package status
+
+import (
+	"syscall"
+)
+
+// bindToDevice is a no-op outside of linux, sockets are bound to the
+// interface's address only and left to the routing table
+func bindToDevice(device string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
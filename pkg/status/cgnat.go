@@ -0,0 +1,132 @@
+package status
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+var (
+	DefaultEchoURL = "https://api.ipify.org"
+
+	// SharedAddressSpace is the RFC 6598 range reserved for carrier grade NAT
+	SharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+)
+
+// PublicAddress asks EchoURL which IPv4 address the request came from. The
+// response may be a bare address or key=value lines with an ip key (ex. a
+// cloudflare cdn-cgi/trace)
+func (s *Status) PublicAddress() *models.EchoReport {
+	report := &models.EchoReport{URL: s.EchoURL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.HTTPTimeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.TermChannel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// the trashcan hands out IPv6 too, only the IPv4 path goes through CGNAT
+	dialer := &net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "tcp4", addr)
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.EchoURL, nil)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		report.Error = fmt.Sprintf("echo endpoint returned %s", resp.Status)
+		return report
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, HTTPBodyLimit))
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	addr := ParseEcho(string(body))
+	if addr == "" {
+		report.Error = "echo endpoint did not return an IPv4 address"
+		return report
+	}
+	report.Address = addr
+
+	return report
+}
+
+// ParseEcho extracts an IPv4 address from an echo endpoint's response body
+func ParseEcho(body string) string {
+	candidates := []string{strings.TrimSpace(body)}
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		if key, val, ok := strings.Cut(scanner.Text(), "="); ok && key == "ip" {
+			candidates = append(candidates, strings.TrimSpace(val))
+		}
+	}
+
+	for _, c := range candidates {
+		if ip := net.ParseIP(c); ip != nil && ip.To4() != nil {
+			return ip.String()
+		}
+	}
+
+	return ""
+}
+
+// DetectCGNAT compares the trashcan's WAN IPv4 address with the public address
+// seen by an echo endpoint. The connection is behind CGNAT if the WAN address is
+// in the shared address space or is translated again before reaching the internet
+func DetectCGNAT(wan string, echo *models.EchoReport) *models.CGNATReport {
+	report := &models.CGNATReport{WANAddress: wan}
+
+	ip := net.ParseIP(wan)
+	if ip == nil || ip.To4() == nil {
+		report.Error = fmt.Sprintf("trashcan did not report a WAN IPv4 address (%q)", wan)
+		return report
+	}
+
+	report.SharedSpace = SharedAddressSpace.Contains(ip)
+	report.CGNAT = report.SharedSpace || ip.IsPrivate()
+
+	if echo == nil {
+		return report
+	}
+	if echo.Error != "" {
+		report.Error = echo.Error
+		return report
+	}
+
+	report.PublicAddress = echo.Address
+	report.Translated = echo.Address != ip.String()
+	report.CGNAT = report.CGNAT || report.Translated
+
+	return report
+}
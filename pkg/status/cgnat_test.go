@@ -0,0 +1,51 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func TestParseEcho(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.9\n":                 "203.0.113.9",
+		"fl=1\nip=203.0.113.9\nts=1\n":  "203.0.113.9",
+		"2607:fb90:1234::1":             "",
+		"<html>not an address</html>\n": "",
+	}
+
+	for body, expected := range cases {
+		if addr := ParseEcho(body); addr != expected {
+			t.Fatalf("Expected %q from %q but got %q", expected, body, addr)
+		}
+	}
+}
+
+func TestDetectCGNAT(t *testing.T) {
+	cases := []struct {
+		name       string
+		wan        string
+		public     string
+		shared     bool
+		translated bool
+		cgnat      bool
+	}{
+		{"shared space", "100.88.12.7", "203.0.113.9", true, true, true},
+		{"translated", "198.51.100.7", "203.0.113.9", false, true, true},
+		{"public", "203.0.113.9", "203.0.113.9", false, false, false},
+	}
+
+	for _, c := range cases {
+		report := DetectCGNAT(c.wan, &models.EchoReport{Address: c.public})
+		if report.Error != "" {
+			t.Fatalf("%s: unexpected error %s", c.name, report.Error)
+		}
+		if report.SharedSpace != c.shared || report.Translated != c.translated || report.CGNAT != c.cgnat {
+			t.Fatalf("%s: expected shared=%v translated=%v cgnat=%v but got %+v", c.name, c.shared, c.translated, c.cgnat, report)
+		}
+	}
+
+	if report := DetectCGNAT("", nil); report.Error == "" {
+		t.Fatal("Expected an error without a WAN address")
+	}
+}
@@ -0,0 +1,190 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// devicePingInterval is the time between echoes pinging through an interface,
+// the same as pro-bing's default
+const devicePingInterval = time.Second
+
+// ErrPingDevice is returned pinging through an interface where sockets cannot
+// be pinned to one. Such pings are reported but not counted as loss
+var ErrPingDevice = errors.New("pinging through an interface is unsupported")
+
+var (
+	// echoIDs is seeded per process so several gomo instances on one host
+	// don't share echo IDs and count each other's replies
+	echoIDs   = rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(os.Getpid())))
+	echoIDsMu sync.Mutex
+)
+
+// echoID returns a random ID for a run of echoes
+func echoID() int {
+	echoIDsMu.Lock()
+	defer echoIDsMu.Unlock()
+
+	return echoIDs.Intn(0xffff)
+}
+
+// pingDevice pings probe's target from an ICMP socket pinned to probe's
+// Device, as pro-bing can only bind its sockets to an address and would leave
+// through the default route. Returns the same statistics as a pinger along
+// with the runs of lost packets
+func (s *Status) pingDevice(probe Probe) (*probing.Statistics, LossRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.DNSTimeout)
+	addrs, err := lookupFamily(ctx, probe.Family, probe.Target)
+	cancel()
+	if err != nil {
+		return nil, LossRun{}, err
+	}
+	dst := addrs[0]
+
+	conn, err := listenDevice(probe.Family, probe.Source, probe.Device, s.Privileged)
+	if err != nil {
+		return nil, LossRun{}, err
+	}
+	defer conn.Close()
+
+	proto, request, reply := 1, icmp.Type(ipv4.ICMPTypeEcho), icmp.Type(ipv4.ICMPTypeEchoReply)
+	if probe.Family == FamilyIPv6 {
+		proto, request, reply = 58, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	var addr net.Addr = &net.UDPAddr{IP: dst}
+	if s.Privileged {
+		addr = &net.IPAddr{IP: dst}
+	}
+	// the kernel replaces the echo ID of datagram ICMP sockets with its own
+	// and only hands them their own replies
+	id := echoID()
+
+	sentAt := make(map[int]time.Time, s.PingCount)
+	sent := make([]int, 0, s.PingCount)
+	received := make(map[int]bool, s.PingCount)
+	var rtts []time.Duration
+
+	buf := make([]byte, 1500)
+	end := time.Now().Add(s.PingTimeout)
+	next := time.Now()
+
+loop:
+	for seq := 0; len(received) < s.PingCount; {
+		select {
+		case <-s.TermChannel:
+			break loop
+		default:
+		}
+
+		now := time.Now()
+		if !now.Before(end) {
+			break
+		}
+		if seq < s.PingCount && !now.Before(next) {
+			msg := icmp.Message{Type: request, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("gomo")}}
+			b, err := msg.Marshal(nil)
+			if err != nil {
+				return nil, LossRun{}, err
+			}
+			if _, err := conn.WriteTo(b, addr); err != nil {
+				return nil, LossRun{}, err
+			}
+			sentAt[seq] = now
+			sent = append(sent, seq)
+			seq++
+			next = next.Add(devicePingInterval)
+		}
+
+		deadline := end
+		if seq < s.PingCount && next.Before(deadline) {
+			deadline = next
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return nil, LossRun{}, err
+		}
+
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return nil, LossRun{}, err
+		}
+
+		// raw sockets see every echo reply to the host
+		if !peerIP(peer).Equal(dst) {
+			continue
+		}
+
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || msg.Type != reply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || (s.Privileged && echo.ID != id) || received[echo.Seq] {
+			continue
+		}
+		if at, ok := sentAt[echo.Seq]; ok {
+			received[echo.Seq] = true
+			rtts = append(rtts, time.Since(at))
+		}
+	}
+
+	return deviceStatistics(probe.Target, dst, len(sent), rtts), LossRuns(sent, received), nil
+}
+
+// peerIP returns the address a reply came from, a *net.IPAddr from raw
+// sockets and a *net.UDPAddr from datagram sockets
+func peerIP(peer net.Addr) net.IP {
+	switch a := peer.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// deviceStatistics fills in pinger statistics from the echoes sent and the
+// round trip times of those answered
+func deviceStatistics(target string, dst net.IP, sent int, rtts []time.Duration) *probing.Statistics {
+	stats := &probing.Statistics{
+		PacketsSent: sent,
+		PacketsRecv: len(rtts),
+		IPAddr:      &net.IPAddr{IP: dst},
+		Addr:        target,
+		Rtts:        rtts,
+	}
+	if sent > 0 {
+		stats.PacketLoss = float64(sent-len(rtts)) / float64(sent) * 100
+	}
+	if len(rtts) == 0 {
+		return stats
+	}
+
+	var total time.Duration
+	stats.MinRtt = rtts[0]
+	for _, rtt := range rtts {
+		total += rtt
+		if rtt < stats.MinRtt {
+			stats.MinRtt = rtt
+		}
+		if rtt > stats.MaxRtt {
+			stats.MaxRtt = rtt
+		}
+	}
+	stats.AvgRtt = total / time.Duration(len(rtts))
+
+	return stats
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package status
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenDevice opens an ICMP socket in family bound to source and pinned to
+// device with SO_BINDTODEVICE. Privileged sockets are raw, otherwise a
+// datagram socket is used which linux lets anyone pin since 5.7
+func listenDevice(family string, source string, device string, privileged bool) (net.PacketConn, error) {
+	if privileged {
+		network := "ip4:icmp"
+		if family == FamilyIPv6 {
+			network = "ip6:ipv6-icmp"
+		}
+		lc := net.ListenConfig{Control: bindToDevice(device)}
+		return lc.ListenPacket(context.Background(), network, source)
+	}
+
+	ip := net.ParseIP(source)
+	domain, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	var sa syscall.Sockaddr
+	if family == FamilyIPv6 {
+		domain, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		sa6 := &syscall.SockaddrInet6{}
+		copy(sa6.Addr[:], ip.To16())
+		sa = sa6
+	} else {
+		sa4 := &syscall.SockaddrInet4{}
+		copy(sa4.Addr[:], ip.To4())
+		sa = sa4
+	}
+
+	fd, err := syscall.Socket(domain, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := syscall.BindToDevice(fd, device); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+
+	return net.FilePacketConn(f)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package status
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// listenDevice fails outside of linux, where sockets can't be pinned to an
+// interface
+func listenDevice(family string, source string, device string, privileged bool) (net.PacketConn, error) {
+	return nil, fmt.Errorf("%w on %s, give the WAN's address instead", ErrPingDevice, runtime.GOOS)
+}
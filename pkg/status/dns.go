@@ -0,0 +1,309 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+const (
+	// GatewayResolverName is the resolver label used for the trashcan's own DNS forwarder
+	GatewayResolverName = "gateway"
+	DNSPort             = "53"
+)
+
+var (
+	// DefaultDNSNames are names answered with the same addresses wherever they
+	// are resolved from, geo balanced names like www.google.com return
+	// disjoint answers per resolver and would always be flagged as mismatches
+	DefaultDNSNames     = []string{"github.com", "cloudflare.com"}
+	DefaultDNSResolvers = []string{"1.1.1.1", "8.8.8.8"}
+	// DefaultDNSResolvers6 are the IPv6 addresses of DefaultDNSResolvers, to
+	// carry IPv6 lookups when comparing families
+	DefaultDNSResolvers6 = []string{"2606:4700:4700::1111", "2001:4860:4860::8888"}
+	DefaultDNSCount      = 3
+	DefaultDNSTimeout    = 5 * time.Second
+)
+
+// DNSResolver is a named DNS server to send lookups to. Family is the address
+// family of Address, empty when it is a hostname
+type DNSResolver struct {
+	Name    string
+	Address string
+	Family  string
+}
+
+// DNSQuery is a single unit of DNS work, a name to resolve through a resolver.
+// A Family carries the lookup over that family and restricts it to A or AAAA
+// records
+type DNSQuery struct {
+	Resolver DNSResolver
+	Name     string
+	Family   string
+}
+
+// NewDNSResolver returns a DNSResolver for address, appending the default DNS port if missing
+func NewDNSResolver(name string, address string) DNSResolver {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, DNSPort)
+	}
+
+	var family string
+	host, _, _ := net.SplitHostPort(address)
+	if ip := net.ParseIP(host); ip != nil {
+		family = FamilyIPv6
+		if ip.To4() != nil {
+			family = FamilyIPv4
+		}
+	}
+
+	return DNSResolver{
+		Name:    name,
+		Address: address,
+		Family:  family,
+	}
+}
+
+// serves returns true if the resolver can be reached over family, a resolver
+// given by hostname is dialed over whichever family is asked for
+func (r DNSResolver) serves(family string) bool {
+	return family == "" || r.Family == "" || r.Family == family
+}
+
+// GatewayResolver returns a DNSResolver pointed at the trashcan's DNS forwarder
+// derived from its web hostname (ex. http://192.168.12.1)
+func GatewayResolver(hostname string) (DNSResolver, error) {
+	host, err := GatewayHost(hostname)
+	if err != nil {
+		return DNSResolver{}, err
+	}
+
+	return NewDNSResolver(GatewayResolverName, host), nil
+}
+
+// Resolve fans every DNSNames and DNSResolvers combination, and address family
+// if Families is set, out to Workers resolvers and returns one report per
+// combination, grouped by resolver in configured order. A family is only
+// asked of the resolvers reachable over it, so IPv6 lookups go to IPv6
+// resolvers and a broken IPv6 path shows up as failed lookups
+func (s *Status) Resolve() []*models.DNSReport {
+	var wg sync.WaitGroup
+
+	families := s.Families
+	if len(families) == 0 {
+		families = []string{""}
+	}
+
+	total := len(s.DNSNames) * len(s.DNSResolvers) * len(families)
+	work := make(chan DNSQuery, total)
+	ret := make(chan *models.DNSReport, total)
+
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go s.ResolveAsync(&wg, work, ret)
+	}
+
+	for _, resolver := range s.DNSResolvers {
+		for _, name := range s.DNSNames {
+			for _, family := range families {
+				if resolver.serves(family) {
+					work <- DNSQuery{Resolver: resolver, Name: name, Family: family}
+				}
+			}
+		}
+	}
+	close(work)
+
+	wg.Wait()
+	close(ret)
+
+	reports := make([]*models.DNSReport, 0, total)
+	for report := range ret {
+		reports = append(reports, report)
+	}
+
+	order := make(map[string]int, len(s.DNSResolvers))
+	for i, resolver := range s.DNSResolvers {
+		order[resolver.Name] = i
+	}
+	nameOrder := make(map[string]int, len(s.DNSNames))
+	for i, name := range s.DNSNames {
+		nameOrder[name] = i
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Resolver != reports[j].Resolver {
+			return order[reports[i].Resolver] < order[reports[j].Resolver]
+		}
+		if reports[i].Name != reports[j].Name {
+			return nameOrder[reports[i].Name] < nameOrder[reports[j].Name]
+		}
+		return reports[i].Family < reports[j].Family
+	})
+
+	MarkMismatches(reports)
+
+	return reports
+}
+
+// ResolveAsync performs each DNSQuery received on work until work is closed
+func (s *Status) ResolveAsync(wg *sync.WaitGroup, work chan DNSQuery, ret chan *models.DNSReport) {
+	defer wg.Done()
+	for query := range work {
+		ret <- s.resolve(query)
+	}
+}
+
+func (s *Status) resolve(query DNSQuery) *models.DNSReport {
+	report := &models.DNSReport{
+		Resolver: query.Resolver.Name,
+		Address:  query.Resolver.Address,
+		Name:     query.Name,
+		Family:   query.Family,
+	}
+
+	address := query.Resolver.Address
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, proto string, _ string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, network(proto, query.Family), address)
+		},
+	}
+
+	answers := make(map[string]bool)
+	var total time.Duration
+
+	for i := 0; i < s.DNSCount; i++ {
+		select {
+		case <-s.TermChannel:
+			return report
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.DNSTimeout)
+		start := time.Now()
+		addrs, err := lookup(ctx, r, query)
+		elapsed := time.Since(start)
+		cancel()
+
+		// a name without records in the family is not a resolver failure
+		var dnsErr *net.DNSError
+		if query.Family != "" && errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			report.NoRecord = true
+			report.Error = err.Error()
+			break
+		}
+
+		report.Lookups++
+		if err != nil {
+			report.Failures++
+			report.Error = err.Error()
+			continue
+		}
+
+		total += elapsed
+		if report.MinLatency == 0 || elapsed < report.MinLatency {
+			report.MinLatency = elapsed
+		}
+		if elapsed > report.MaxLatency {
+			report.MaxLatency = elapsed
+		}
+		for _, addr := range addrs {
+			answers[addr] = true
+		}
+	}
+
+	if succeeded := report.Lookups - report.Failures; succeeded > 0 {
+		report.AvgLatency = total / time.Duration(succeeded)
+	}
+	if report.Lookups > 0 {
+		report.FailureRate = float64(report.Failures) / float64(report.Lookups)
+	}
+
+	for addr := range answers {
+		report.Answers = append(report.Answers, addr)
+	}
+	sort.Strings(report.Answers)
+
+	return report
+}
+
+// lookup resolves the query's name through r, restricted to A or AAAA records
+// if the query has a Family
+func lookup(ctx context.Context, r *net.Resolver, query DNSQuery) ([]string, error) {
+	if query.Family == "" {
+		return r.LookupHost(ctx, query.Name)
+	}
+
+	ips, err := r.LookupIP(ctx, network("ip", query.Family), query.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+	return addrs, nil
+}
+
+// MarkMismatches flags reports whose answers share no network with the answers
+// every other resolver returned for the same name. CDN backed names commonly
+// return different addresses per resolver so answers are compared by /24, or
+// /48 for IPv6, and only a fully disjoint answer set counts as a mismatch.
+// Resolvers without answers are left to their failures rather than flagged,
+// and answers are only compared within the same address family
+func MarkMismatches(reports []*models.DNSReport) {
+	byName := make(map[string][]*models.DNSReport)
+	for _, r := range reports {
+		r.Mismatch = false
+		if len(r.Answers) == 0 {
+			continue
+		}
+		key := r.Name + "/" + r.Family
+		byName[key] = append(byName[key], r)
+	}
+
+	for _, group := range byName {
+		for _, r := range group {
+			others := make(map[string]bool)
+			for _, o := range group {
+				if o == r {
+					continue
+				}
+				for _, addr := range o.Answers {
+					others[answerNetwork(addr)] = true
+				}
+			}
+
+			if len(others) == 0 {
+				continue
+			}
+
+			r.Mismatch = true
+			for _, addr := range r.Answers {
+				if others[answerNetwork(addr)] {
+					r.Mismatch = false
+					break
+				}
+			}
+		}
+	}
+}
+
+// answerNetwork returns the /24, or /48 for IPv6, an answer falls in
+func answerNetwork(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
@@ -0,0 +1,129 @@
+package status
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func TestMarkMismatches(t *testing.T) {
+	reports := []*models.DNSReport{
+		{Resolver: "gateway", Name: "example.com", Answers: []string{"10.0.0.1"}},
+		{Resolver: "1.1.1.1", Name: "example.com", Answers: []string{"93.184.216.34"}},
+		{Resolver: "8.8.8.8", Name: "example.com", Answers: []string{"93.184.216.34", "93.184.216.35"}},
+		{Resolver: "gateway", Name: "github.com", Answers: []string{"140.82.112.3"}},
+		{Resolver: "1.1.1.1", Name: "github.com", Answers: []string{"140.82.112.4"}},
+		{Resolver: "8.8.8.8", Name: "github.com", Answers: []string{"140.82.113.3"}},
+	}
+
+	MarkMismatches(reports)
+
+	// the same /24 is not a mismatch, a neighbouring one is
+	expected := []bool{true, false, false, false, false, true}
+	for i, r := range reports {
+		if r.Mismatch != expected[i] {
+			t.Fatalf("Expected mismatch %v for %s via %s but got %v", expected[i], r.Name, r.Resolver, r.Mismatch)
+		}
+	}
+}
+
+func TestMarkMismatchesFailedResolver(t *testing.T) {
+	reports := []*models.DNSReport{
+		{Resolver: "gateway", Name: "github.com", Failures: 3, Error: "i/o timeout"},
+		{Resolver: "1.1.1.1", Name: "github.com", Answers: []string{"140.82.112.3"}},
+		{Resolver: "8.8.8.8", Name: "github.com", Answers: []string{"140.82.112.4"}},
+	}
+
+	MarkMismatches(reports)
+
+	for _, r := range reports {
+		if r.Mismatch {
+			t.Fatalf("Expected no mismatch via %s when the gateway only failed", r.Resolver)
+		}
+	}
+}
+
+func TestNewDNSResolver(t *testing.T) {
+	tests := map[string]struct {
+		address string
+		family  string
+	}{
+		"1.1.1.1":         {"1.1.1.1:53", FamilyIPv4},
+		"9.9.9.9:5353":    {"9.9.9.9:5353", FamilyIPv4},
+		"2606:4700::1":    {"[2606:4700::1]:53", FamilyIPv6},
+		"dns.example.com": {"dns.example.com:53", ""},
+	}
+
+	for in, expected := range tests {
+		r := NewDNSResolver(in, in)
+		if r.Address != expected.address {
+			t.Fatalf("Expected address %s for %s but got %s", expected.address, in, r.Address)
+		}
+		if r.Family != expected.family {
+			t.Fatalf("Expected family %q for %s but got %q", expected.family, in, r.Family)
+		}
+	}
+
+	gw, err := GatewayResolver("http://192.168.12.1")
+	if err != nil {
+		t.Fatalf("Failed to build gateway resolver: %v", err)
+	}
+	if gw.Address != "192.168.12.1:53" {
+		t.Fatalf("Expected gateway resolver 192.168.12.1:53 but got %s", gw.Address)
+	}
+}
+
+func TestResolveFamilies(t *testing.T) {
+	listen := func(address string) (net.PacketConn, *int32) {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			t.Skipf("Cannot listen on %s: %v", address, err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		var queries int32
+		go func() {
+			buf := make([]byte, 512)
+			for {
+				if _, _, err := conn.ReadFrom(buf); err != nil {
+					return
+				}
+				atomic.AddInt32(&queries, 1)
+			}
+		}()
+		return conn, &queries
+	}
+
+	conn4, queries4 := listen("127.0.0.1:0")
+	conn6, queries6 := listen("[::1]:0")
+
+	s := &Status{
+		Workers:  2,
+		Families: []string{FamilyIPv4, FamilyIPv6},
+		DNSNames: []string{"example.com"},
+		DNSResolvers: []DNSResolver{
+			NewDNSResolver("v4", conn4.LocalAddr().String()),
+			NewDNSResolver("v6", conn6.LocalAddr().String()),
+		},
+		DNSCount:    1,
+		DNSTimeout:  200 * time.Millisecond,
+		TermChannel: make(chan interface{}),
+	}
+
+	reports := s.Resolve()
+	if len(reports) != 2 {
+		t.Fatalf("Expected a query per resolver family but got %d reports", len(reports))
+	}
+	expected := map[string]string{"v4": FamilyIPv4, "v6": FamilyIPv6}
+	for _, r := range reports {
+		if r.Family != expected[r.Resolver] {
+			t.Fatalf("Expected %s to only be asked over %s but got %s", r.Resolver, expected[r.Resolver], r.Family)
+		}
+	}
+	if n4, n6 := atomic.LoadInt32(queries4), atomic.LoadInt32(queries6); n4 == 0 || n6 == 0 {
+		t.Fatalf("Expected queries over both families but got %d over IPv4 and %d over IPv6", n4, n6)
+	}
+}
@@ -0,0 +1,232 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+const (
+	FamilyIPv4 = "ip4"
+	FamilyIPv6 = "ip6"
+)
+
+// DualStack lists both address families in the order they are probed
+var DualStack = []string{FamilyIPv4, FamilyIPv6}
+
+// NoRecordError means a target has no address in the family it was probed
+// over, which says nothing about that family's health
+type NoRecordError struct {
+	Host   string
+	Family string
+}
+
+func (e *NoRecordError) Error() string {
+	return fmt.Sprintf("%s has no %s address", e.Host, FamilyName(e.Family))
+}
+
+// IsNoRecord returns true if err is or wraps a NoRecordError
+func IsNoRecord(err error) bool {
+	var noRecord *NoRecordError
+	return errors.As(err, &noRecord)
+}
+
+// Probe is a single unit of ping or TCP work, a target to reach over an
+// address family. An empty Family lets the resolver pick. Source and Device
+// bind the probe to a local address and interface, empty for the default route
+type Probe struct {
+	Target string
+	Family string
+	Source string
+	Device string
+}
+
+// key identifies the probe in per target state such as loss streaks
+func (p Probe) key() string {
+	key := p.Target
+	if p.Family != "" {
+		key += "/" + p.Family
+	}
+	if p.Source != "" {
+		key += "@" + p.Source
+	}
+	return key
+}
+
+// FamilyName returns the display name of an address family
+func FamilyName(family string) string {
+	switch family {
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	}
+	return "any"
+}
+
+// probes returns one Probe per target for each of Families in target order, or
+// a single family agnostic Probe per target if Families is empty
+func (s *Status) probes(targets []string) []Probe {
+	families := s.Families
+	if len(families) == 0 {
+		families = []string{""}
+	}
+
+	probes := make([]Probe, 0, len(targets)*len(families))
+	for _, target := range targets {
+		for _, family := range families {
+			probes = append(probes, Probe{Target: target, Family: family})
+		}
+	}
+
+	return probes
+}
+
+// network restricts a base network name such as "tcp" or "ip" to family
+func network(base string, family string) string {
+	switch family {
+	case FamilyIPv4:
+		return base + "4"
+	case FamilyIPv6:
+		return base + "6"
+	}
+	return base
+}
+
+// inFamily returns true if ip belongs to family, any ip belongs to no family
+func inFamily(ip net.IP, family string) bool {
+	switch family {
+	case FamilyIPv4:
+		return ip.To4() != nil
+	case FamilyIPv6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// lookupFamily resolves host to its addresses in family. A host with no
+// address in family returns a NoRecordError
+func lookupFamily(ctx context.Context, family string, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if !inFamily(ip, family) {
+			return nil, &NoRecordError{Host: host, Family: family}
+		}
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIP(ctx, network("ip", family), host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		var addrErr *net.AddrError
+		if family != "" && ((errors.As(err, &dnsErr) && dnsErr.IsNotFound) || errors.As(err, &addrErr)) {
+			return nil, &NoRecordError{Host: host, Family: family}
+		}
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &NoRecordError{Host: host, Family: family}
+	}
+
+	return addrs, nil
+}
+
+// CompareFamilies summarizes ping, TCP and DNS results per address family and
+// flags a family as broken when every probe over it failed while the other
+// family worked. Happy eyeballs hides a broken family from most applications
+// until one of them does not fall back
+func CompareFamilies(families []string, report *models.StatusReport) []*models.FamilyReport {
+	summaries := make([]*models.FamilyReport, 0, len(families))
+	byFamily := make(map[string]*models.FamilyReport, len(families))
+	for _, family := range families {
+		f := &models.FamilyReport{Family: family}
+		summaries = append(summaries, f)
+		byFamily[family] = f
+	}
+
+	pingLatency := make(map[string]time.Duration)
+	pingLatencyCount := make(map[string]int)
+	connect := make(map[string]time.Duration)
+	connectCount := make(map[string]int)
+	lookups := make(map[string]int)
+
+	for _, p := range report.Pings {
+		f, ok := byFamily[p.Family]
+		if !ok || p.NoRecord {
+			continue
+		}
+		f.Probes++
+		f.Pings++
+		if p.Body == nil {
+			f.Loss++
+			continue
+		}
+		f.Loss += p.Body.PacketLoss / 100
+		if p.Error == nil && p.Body.PacketsRecv > 0 {
+			f.Succeeded++
+			pingLatency[p.Family] += p.Body.AvgResponseTime
+			pingLatencyCount[p.Family]++
+		}
+	}
+
+	for _, t := range report.TCP {
+		f, ok := byFamily[t.Family]
+		if !ok || t.NoRecord {
+			continue
+		}
+		f.Probes++
+		if t.Up {
+			f.Succeeded++
+			connect[t.Family] += t.Phases.Connect
+			connectCount[t.Family]++
+		}
+	}
+
+	for _, d := range report.DNS {
+		f, ok := byFamily[d.Family]
+		if !ok || d.NoRecord {
+			continue
+		}
+		f.DNSFailures += d.Failures
+		lookups[d.Family] += d.Lookups
+	}
+
+	for _, f := range summaries {
+		if f.Pings > 0 {
+			f.Loss /= float64(f.Pings)
+		}
+		if n := pingLatencyCount[f.Family]; n > 0 {
+			f.Latency = pingLatency[f.Family] / time.Duration(n)
+		}
+		if n := connectCount[f.Family]; n > 0 {
+			f.Connect = connect[f.Family] / time.Duration(n)
+		}
+		if n := lookups[f.Family]; n > 0 {
+			f.DNSFailureRate = float64(f.DNSFailures) / float64(n)
+		}
+		f.Reachable = f.Succeeded > 0
+	}
+
+	for _, f := range summaries {
+		if f.Probes == 0 {
+			f.Reason = fmt.Sprintf("no targets have an %s address", FamilyName(f.Family))
+			continue
+		}
+		if f.Reachable {
+			continue
+		}
+		f.Reason = fmt.Sprintf("every %s probe failed", FamilyName(f.Family))
+		for _, other := range summaries {
+			if other != f && other.Reachable {
+				f.Broken = true
+				f.Reason = fmt.Sprintf("every %s probe failed while %s works", FamilyName(f.Family), FamilyName(other.Family))
+				break
+			}
+		}
+	}
+
+	return summaries
+}
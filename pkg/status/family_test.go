@@ -0,0 +1,73 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func familyPing(host string, family string, sent int, recv int) *models.PingReportReturn {
+	ping := fakePing(host, sent, recv, 20*time.Millisecond)
+	ping.Family = family
+	return ping
+}
+
+func TestCompareFamilies(t *testing.T) {
+	report := &models.StatusReport{
+		Pings: []*models.PingReportReturn{
+			familyPing("a", FamilyIPv4, 10, 9),
+			familyPing("a", FamilyIPv6, 10, 0),
+			{Hostname: "b", Family: FamilyIPv6, NoRecord: true},
+		},
+		TCP: []*models.TCPReport{
+			{Target: "a:443", Family: FamilyIPv4, Up: true, Phases: models.ProbePhases{Connect: 30 * time.Millisecond}},
+			{Target: "a:443", Family: FamilyIPv6, Error: "i/o timeout"},
+		},
+		DNS: []*models.DNSReport{
+			{Name: "a", Family: FamilyIPv4, Lookups: 3},
+			{Name: "a", Family: FamilyIPv6, Lookups: 3, Failures: 1},
+		},
+	}
+
+	families := CompareFamilies(DualStack, report)
+	v4, v6 := families[0], families[1]
+
+	if !v4.Reachable || v4.Broken {
+		t.Fatalf("Expected IPv4 reachable and not broken but got %+v", v4)
+	}
+	if v4.Loss != 0.1 || v4.Connect != 30*time.Millisecond {
+		t.Fatalf("Expected IPv4 loss 0.1 and 30ms connect but got %f and %s", v4.Loss, v4.Connect)
+	}
+
+	if v6.Reachable || !v6.Broken {
+		t.Fatalf("Expected IPv6 broken but got %+v", v6)
+	}
+	// b has no AAAA record so only a's ping and TCP probes count
+	if v6.Probes != 2 {
+		t.Fatalf("Expected 2 IPv6 probes but got %d", v6.Probes)
+	}
+	if v6.DNSFailureRate != float64(1)/3 {
+		t.Fatalf("Expected an IPv6 DNS failure rate of 1/3 but got %f", v6.DNSFailureRate)
+	}
+
+	// both families failing is an outage, not a broken family
+	report.Pings = report.Pings[1:]
+	report.TCP = report.TCP[1:]
+	for _, f := range CompareFamilies(DualStack, report) {
+		if f.Broken {
+			t.Fatalf("Expected no broken family when neither works but got %+v", f)
+		}
+	}
+}
+
+func TestLookupFamily(t *testing.T) {
+	if _, err := lookupFamily(context.Background(), FamilyIPv4, "::1"); !IsNoRecord(err) {
+		t.Fatalf("Expected no IPv4 record for ::1 but got %v", err)
+	}
+	addrs, err := lookupFamily(context.Background(), FamilyIPv6, "::1")
+	if err != nil || len(addrs) != 1 {
+		t.Fatalf("Expected ::1 but got %v, %v", addrs, err)
+	}
+}
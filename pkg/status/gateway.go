@@ -0,0 +1,166 @@
+package status
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+const (
+	// VerdictHealthy means neither segment exceeded its thresholds
+	VerdictHealthy = "healthy"
+	// VerdictLAN means the hop to the gateway is lossy or slow, WiFi or Ethernet trouble
+	VerdictLAN = "lan"
+	// VerdictWAN means the gateway hop is clean but the internet targets are not, cellular or upstream trouble
+	VerdictWAN = "wan"
+	// VerdictBoth means both segments exceeded their thresholds
+	VerdictBoth = "both"
+	// VerdictUnknown means there were not enough ping results to tell
+	VerdictUnknown = "unknown"
+)
+
+var (
+	Verdicts = []string{VerdictHealthy, VerdictLAN, VerdictWAN, VerdictBoth, VerdictUnknown}
+
+	// LossThreshold is the loss ratio above which a segment is considered degraded
+	LossThreshold = 0.05
+	// LANLatencyThreshold is the average gateway RTT above which the local network
+	// is considered degraded
+	LANLatencyThreshold = 30 * time.Millisecond
+	// WANLatencyThreshold is the average RTT added beyond the gateway above which
+	// the WAN is considered degraded
+	WANLatencyThreshold = 150 * time.Millisecond
+)
+
+// GatewayHost returns the host of the trashcan's web hostname (ex. http://192.168.12.1)
+func GatewayHost(hostname string) (string, error) {
+	u, err := url.Parse(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		host = hostname
+	}
+
+	return host, nil
+}
+
+// PingGateway pings the Gateway host
+func (s *Status) PingGateway() *models.PingReportReturn {
+	var wg sync.WaitGroup
+
+	work := make(chan Probe, 1)
+	ret := make(chan *models.PingReportReturn, 1)
+
+	wg.Add(1)
+	go s.PingAsync(&wg, work, ret)
+
+	work <- Probe{Target: s.Gateway}
+	close(work)
+
+	wg.Wait()
+
+	return <-ret
+}
+
+// gatewayFamily returns the pings comparable with the gateway ping. The
+// trashcan's LAN address is IPv4 so IPv6 pings, and targets skipped for having
+// no address in a family, are left out when probing both families
+func gatewayFamily(pings []*models.PingReportReturn) []*models.PingReportReturn {
+	ret := make([]*models.PingReportReturn, 0, len(pings))
+	for _, p := range pings {
+		if p.Family != FamilyIPv6 && !p.NoRecord {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// Isolate splits loss and latency between the local network, as seen pinging
+// the gateway, and the WAN, as seen pinging internet targets through it
+func Isolate(gateway *models.PingReportReturn, pings []*models.PingReportReturn) *models.LinkSplit {
+	split := &models.LinkSplit{Verdict: VerdictUnknown}
+
+	if gateway == nil || gateway.Body == nil || gateway.Body.PacketsSent == 0 {
+		split.Reason = "no gateway ping results"
+		return split
+	}
+
+	split.LAN = models.SegmentStats{
+		Loss:    gateway.Body.PacketLoss / 100,
+		Latency: gateway.Body.AvgResponseTime,
+		Jitter:  gateway.Body.Jitter,
+	}
+
+	// targets that failed outright carry 100% loss so they count against the WAN
+	var loss float64
+	var targets, answered int
+	var total, jitter time.Duration
+	for _, p := range pings {
+		if p.Body == nil {
+			continue
+		}
+		targets++
+		loss += p.Body.PacketLoss / 100
+		if p.Body.PacketsRecv > 0 {
+			total += p.Body.AvgResponseTime
+			jitter += p.Body.Jitter
+			answered++
+		}
+	}
+
+	if targets == 0 {
+		split.Reason = "no internet ping results"
+		return split
+	}
+
+	// loss beyond the gateway is whatever the LAN does not explain
+	internetLoss := loss / float64(targets)
+	if split.LAN.Loss < 1 {
+		split.WAN.Loss = 1 - (1-internetLoss)/(1-split.LAN.Loss)
+	}
+	if split.WAN.Loss < 0 {
+		split.WAN.Loss = 0
+	}
+	if answered > 0 {
+		split.WAN.Latency = total/time.Duration(answered) - split.LAN.Latency
+		if split.WAN.Latency < 0 {
+			split.WAN.Latency = 0
+		}
+		split.WAN.Jitter = jitter / time.Duration(answered)
+	}
+
+	var lan, wan []string
+	if split.LAN.Loss > LossThreshold {
+		lan = append(lan, fmt.Sprintf("%.0f%% loss to gateway", split.LAN.Loss*100))
+	}
+	if split.LAN.Loss < 1 && split.LAN.Latency > LANLatencyThreshold {
+		lan = append(lan, fmt.Sprintf("%s to gateway", split.LAN.Latency.Round(time.Millisecond)))
+	}
+	if split.WAN.Loss > LossThreshold {
+		wan = append(wan, fmt.Sprintf("%.0f%% loss beyond gateway", split.WAN.Loss*100))
+	}
+	if split.WAN.Latency > WANLatencyThreshold {
+		wan = append(wan, fmt.Sprintf("%s added beyond gateway", split.WAN.Latency.Round(time.Millisecond)))
+	}
+
+	switch {
+	case len(lan) > 0 && len(wan) > 0:
+		split.Verdict = VerdictBoth
+	case len(lan) > 0:
+		split.Verdict = VerdictLAN
+	case len(wan) > 0:
+		split.Verdict = VerdictWAN
+	default:
+		split.Verdict = VerdictHealthy
+	}
+	split.Reason = strings.Join(append(lan, wan...), ", ")
+
+	return split
+}
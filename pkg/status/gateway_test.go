@@ -0,0 +1,83 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func fakePing(host string, sent int, recv int, avg time.Duration) *models.PingReportReturn {
+	return &models.PingReportReturn{
+		Hostname: host,
+		Body: &models.PingReport{
+			Hostname:        host,
+			PacketsSent:     sent,
+			PacketsRecv:     recv,
+			PacketLoss:      float64(sent-recv) / float64(sent) * 100,
+			AvgResponseTime: avg,
+		},
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	cases := []struct {
+		name     string
+		gateway  *models.PingReportReturn
+		pings    []*models.PingReportReturn
+		expected string
+	}{
+		{
+			"healthy",
+			fakePing("192.168.12.1", 10, 10, 2*time.Millisecond),
+			[]*models.PingReportReturn{fakePing("a", 10, 10, 40*time.Millisecond)},
+			VerdictHealthy,
+		},
+		{
+			"lossy wifi",
+			fakePing("192.168.12.1", 10, 7, 4*time.Millisecond),
+			[]*models.PingReportReturn{fakePing("a", 10, 7, 40*time.Millisecond)},
+			VerdictLAN,
+		},
+		{
+			"lossy tower",
+			fakePing("192.168.12.1", 10, 10, 2*time.Millisecond),
+			[]*models.PingReportReturn{fakePing("a", 10, 6, 40*time.Millisecond), fakePing("b", 10, 8, 50*time.Millisecond)},
+			VerdictWAN,
+		},
+		{
+			"slow tower",
+			fakePing("192.168.12.1", 10, 10, 2*time.Millisecond),
+			[]*models.PingReportReturn{fakePing("a", 10, 10, 400*time.Millisecond)},
+			VerdictWAN,
+		},
+		{
+			"both",
+			fakePing("192.168.12.1", 10, 10, 80*time.Millisecond),
+			[]*models.PingReportReturn{fakePing("a", 10, 5, 400*time.Millisecond)},
+			VerdictBoth,
+		},
+		{
+			"no gateway",
+			&models.PingReportReturn{Hostname: "192.168.12.1"},
+			[]*models.PingReportReturn{fakePing("a", 10, 10, 40*time.Millisecond)},
+			VerdictUnknown,
+		},
+	}
+
+	for _, c := range cases {
+		split := Isolate(c.gateway, c.pings)
+		if split.Verdict != c.expected {
+			t.Fatalf("%s: expected verdict %s but got %s (%s)", c.name, c.expected, split.Verdict, split.Reason)
+		}
+	}
+
+	// 30% loss to the gateway explains all of the 30% loss to the internet
+	split := Isolate(cases[1].gateway, cases[1].pings)
+	if split.WAN.Loss != 0 {
+		t.Fatalf("Expected no loss attributed to the WAN but got %f", split.WAN.Loss)
+	}
+	if split.WAN.Latency != 36*time.Millisecond {
+		t.Fatalf("Expected 36ms added beyond the gateway but got %s", split.WAN.Latency)
+	}
+}
@@ -0,0 +1,189 @@
+package status
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+const (
+	// HTTPBodyLimit caps how much of a probe response body is read
+	HTTPBodyLimit = 1 << 20
+)
+
+var (
+	DefaultHTTPTargets       = []string{"https://www.google.com/generate_204"}
+	DefaultHTTPTimeout       = 10 * time.Second
+	DefaultHTTPExpectedCodes = []int{200, 204}
+)
+
+// Fetch fans HTTPTargets out to Workers clients and returns one report per
+// URL in the same order as HTTPTargets
+func (s *Status) Fetch() []*models.HTTPReport {
+	var wg sync.WaitGroup
+
+	work := make(chan string, len(s.HTTPTargets))
+	ret := make(chan *models.HTTPReport, len(s.HTTPTargets))
+
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go s.FetchAsync(&wg, work, ret)
+	}
+
+	for _, target := range s.HTTPTargets {
+		work <- target
+	}
+	close(work)
+
+	wg.Wait()
+	close(ret)
+
+	byURL := make(map[string]*models.HTTPReport, len(s.HTTPTargets))
+	for report := range ret {
+		byURL[report.URL] = report
+	}
+
+	reports := make([]*models.HTTPReport, 0, len(s.HTTPTargets))
+	for _, target := range s.HTTPTargets {
+		if report, ok := byURL[target]; ok {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports
+}
+
+// FetchAsync GETs each URL received on work until work is closed
+func (s *Status) FetchAsync(wg *sync.WaitGroup, work chan string, ret chan *models.HTTPReport) {
+	defer wg.Done()
+	for target := range work {
+		ret <- s.fetch(target)
+	}
+}
+
+func (s *Status) fetch(target string) *models.HTTPReport {
+	report := &models.HTTPReport{URL: target}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.HTTPTimeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.TermChannel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// the trace fires from the transport's dial goroutines, which may still be
+	// running after a timeout, and happy eyeballs dials addresses concurrently
+	var mu sync.Mutex
+	var phases models.ProbePhases
+	var dnsStart, tlsStart, firstByte time.Time
+	connectStart := make(map[string]time.Time)
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart = time.Now()
+			mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mu.Lock()
+			phases.DNS = time.Since(dnsStart)
+			mu.Unlock()
+		},
+		ConnectStart: func(network string, addr string) {
+			mu.Lock()
+			connectStart[network+addr] = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(network string, addr string, err error) {
+			mu.Lock()
+			if err == nil || phases.Connect == 0 {
+				phases.Connect = time.Since(connectStart[network+addr])
+			}
+			mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			phases.TLS = time.Since(tlsStart)
+			mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			firstByte = time.Now()
+			mu.Unlock()
+		},
+	}
+	// copies the phases seen so far into the report once the request is done
+	collect := func(start time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		report.Phases.DNS = phases.DNS
+		report.Phases.Connect = phases.Connect
+		report.Phases.TLS = phases.TLS
+		if !firstByte.IsZero() {
+			report.Phases.TTFB = firstByte.Sub(start)
+		}
+		report.Phases.Total = time.Since(start)
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "GET", target, nil)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		collect(start)
+		report.Error = err.Error()
+		return report
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, io.LimitReader(resp.Body, HTTPBodyLimit))
+	collect(start)
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	report.StatusCode = resp.StatusCode
+	report.Expected = s.expectedCode(resp.StatusCode)
+	report.Up = report.Expected && err == nil
+
+	return report
+}
+
+func (s *Status) expectedCode(code int) bool {
+	if len(s.HTTPExpectedCodes) == 0 {
+		return code >= 200 && code < 400
+	}
+	for _, expected := range s.HTTPExpectedCodes {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
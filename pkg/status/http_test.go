@@ -0,0 +1,39 @@
+package status
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/broken") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := NewStatus(0, 2, nil)
+	defer s.Stop()
+	s.HTTPTargets = []string{srv.URL + "/ok", srv.URL + "/broken"}
+
+	reports := s.Fetch()
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports but got %d", len(reports))
+	}
+
+	ok, broken := reports[0], reports[1]
+	if !ok.Up || ok.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected %s to be up with 204 but got %d (%s)", ok.URL, ok.StatusCode, ok.Error)
+	}
+	if ok.Phases.Total <= 0 || ok.Phases.TTFB <= 0 {
+		t.Fatalf("Expected total and ttfb phases to be recorded but got %+v", ok.Phases)
+	}
+	if broken.Up || broken.Expected || broken.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected %s to be down with 500 but got %d", broken.URL, broken.StatusCode)
+	}
+}
@@ -0,0 +1,180 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+const (
+	// MinMTU is the smallest path MTU searched for, every IPv4 host must accept
+	// datagrams of this size
+	MinMTU = 576
+	// ICMPEchoOverhead is the size of the IPv4 and ICMP echo headers
+	ICMPEchoOverhead = 28
+)
+
+var (
+	DefaultMTUTargets = []string{"www.google.com"}
+	DefaultMTUMax     = 1500
+	DefaultMTUTimeout = time.Second
+	DefaultMTURetries = 2
+)
+
+// DiscoverMTU fans MTUTargets out to Workers probers and returns one report per
+// target in the same order as MTUTargets
+func (s *Status) DiscoverMTU() []*models.MTUReport {
+	var wg sync.WaitGroup
+
+	work := make(chan string, len(s.MTUTargets))
+	ret := make(chan *models.MTUReport, len(s.MTUTargets))
+
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go s.DiscoverMTUAsync(&wg, work, ret)
+	}
+
+	for _, target := range s.MTUTargets {
+		work <- target
+	}
+	close(work)
+
+	wg.Wait()
+	close(ret)
+
+	byTarget := make(map[string]*models.MTUReport, len(s.MTUTargets))
+	for report := range ret {
+		byTarget[report.Target] = report
+	}
+
+	reports := make([]*models.MTUReport, 0, len(s.MTUTargets))
+	for _, target := range s.MTUTargets {
+		if report, ok := byTarget[target]; ok {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports
+}
+
+// DiscoverMTUAsync discovers the path MTU to each target received on work until
+// work is closed
+func (s *Status) DiscoverMTUAsync(wg *sync.WaitGroup, work chan string, ret chan *models.MTUReport) {
+	defer wg.Done()
+	for target := range work {
+		ret <- s.discoverMTU(target)
+	}
+}
+
+func (s *Status) discoverMTU(target string) *models.MTUReport {
+	report := &models.MTUReport{Target: target}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.TermChannel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ip := net.ParseIP(target).To4()
+	if ip == nil {
+		lookupCtx, lookupCancel := context.WithTimeout(ctx, s.DNSTimeout)
+		addrs, err := net.DefaultResolver.LookupIP(lookupCtx, "ip4", target)
+		lookupCancel()
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		ip = addrs[0]
+	}
+	report.Address = ip.String()
+
+	conn, err := listenDF(s.Privileged)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	defer conn.Close()
+
+	seq := 0
+	probe := func(size int) (bool, int, error) {
+		for i := 0; i < s.MTURetries; i++ {
+			if ctx.Err() != nil {
+				return false, 0, ctx.Err()
+			}
+			seq++
+			report.Probes++
+			fits, nextHop, err := conn.echo(ip, size, seq, s.MTUTimeout)
+			if err != nil || fits || nextHop > 0 {
+				return fits, nextHop, err
+			}
+		}
+		return false, 0, nil
+	}
+
+	report.MTU, err = SearchMTU(MinMTU, s.MTUMax, probe)
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	return report
+}
+
+// SearchMTU returns the largest packet size between min and max that probe
+// reports fits through the path. probe may also return the next hop MTU from a
+// fragmentation needed reply to shortcut the search
+func SearchMTU(min int, max int, probe func(size int) (bool, int, error)) (int, error) {
+	fits, _, err := probe(min)
+	if err != nil {
+		return 0, err
+	}
+	if !fits {
+		return 0, fmt.Errorf("no reply to %d byte probes", min)
+	}
+
+	// most paths carry a full sized packet so try that first
+	fits, nextHop, err := probe(max)
+	if err != nil {
+		return 0, err
+	}
+	if fits {
+		return max, nil
+	}
+
+	good, bad := min, max
+	if nextHop > good && nextHop < bad {
+		fits, _, err := probe(nextHop)
+		if err != nil {
+			return good, err
+		}
+		if fits {
+			good = nextHop
+			bad = nextHop + 1
+		} else {
+			bad = nextHop
+		}
+	}
+
+	for bad-good > 1 {
+		mid := (good + bad) / 2
+		fits, _, err := probe(mid)
+		if err != nil {
+			return good, err
+		}
+		if fits {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+
+	return good, nil
+}
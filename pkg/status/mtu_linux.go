@@ -0,0 +1,162 @@
+//go:build linux
+
+package status
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// dfConn sends ICMP echoes with the don't fragment bit set
+type dfConn struct {
+	conn       net.PacketConn
+	privileged bool
+	id         int
+}
+
+// listenDF opens an ICMP socket which sets the don't fragment bit and ignores
+// the kernel's cached path MTU so every probe size is really sent
+func listenDF(privileged bool) (*dfConn, error) {
+	setDF := func(fd uintptr) error {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_PROBE)
+	}
+
+	if privileged {
+		lc := net.ListenConfig{
+			Control: func(network, address string, c syscall.RawConn) error {
+				var sockErr error
+				if err := c.Control(func(fd uintptr) { sockErr = setDF(fd) }); err != nil {
+					return err
+				}
+				return sockErr
+			},
+		}
+		conn, err := lc.ListenPacket(context.Background(), "ip4:icmp", "0.0.0.0")
+		if err != nil {
+			return nil, err
+		}
+		return &dfConn{conn: conn, privileged: true, id: os.Getpid() & 0xffff}, nil
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := setDF(uintptr(fd)); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{}); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+
+	conn, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// the kernel replaces the echo ID of datagram ICMP sockets with its own
+	return &dfConn{conn: conn}, nil
+}
+
+func (c *dfConn) Close() error {
+	return c.conn.Close()
+}
+
+// echo sends a single echo of size bytes including headers to dst and waits up
+// to timeout for the reply. A reply means the size fits, a fragmentation needed
+// error returns the next hop MTU if the router included one
+func (c *dfConn) echo(dst net.IP, size int, seq int, timeout time.Duration) (bool, int, error) {
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{
+			ID:   c.id,
+			Seq:  seq & 0xffff,
+			Data: make([]byte, size-ICMPEchoOverhead),
+		},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return false, 0, err
+	}
+
+	var addr net.Addr = &net.UDPAddr{IP: dst}
+	if c.privileged {
+		addr = &net.IPAddr{IP: dst}
+	}
+
+	if _, err := c.conn.WriteTo(b, addr); err != nil {
+		// larger than the local interface MTU
+		if errors.Is(err, syscall.EMSGSIZE) {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return false, 0, err
+	}
+
+	buf := make([]byte, size+ICMPEchoOverhead)
+	for {
+		n, _, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return false, 0, nil
+			}
+			return false, 0, err
+		}
+
+		reply, err := icmp.ParseMessage(1, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		switch reply.Type {
+		case ipv4.ICMPTypeEchoReply:
+			echo, ok := reply.Body.(*icmp.Echo)
+			if ok && echo.Seq == seq&0xffff && (!c.privileged || echo.ID == c.id) {
+				return true, 0, nil
+			}
+		case ipv4.ICMPTypeDestinationUnreachable:
+			// code 4 is fragmentation needed, the next hop MTU is in the
+			// otherwise unused second half of the header
+			if reply.Code == 4 && n >= 8 && c.quotes(buf[8:n], seq) {
+				return false, int(binary.BigEndian.Uint16(buf[6:8])), nil
+			}
+		}
+	}
+}
+
+// quotes returns true if an ICMP error's payload quotes one of our echoes
+func (c *dfConn) quotes(data []byte, seq int) bool {
+	if len(data) < 1 {
+		return false
+	}
+	ihl := int(data[0]&0x0f) * 4
+	if len(data) < ihl+8 {
+		return false
+	}
+	quoted := data[ihl:]
+	if quoted[0] != byte(ipv4.ICMPTypeEcho) {
+		return false
+	}
+	if int(binary.BigEndian.Uint16(quoted[6:8])) != seq&0xffff {
+		return false
+	}
+	return !c.privileged || int(binary.BigEndian.Uint16(quoted[4:6])) == c.id
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package status
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+)
+
+type dfConn struct{}
+
+func listenDF(privileged bool) (*dfConn, error) {
+	return nil, fmt.Errorf("path MTU discovery is not supported on %s", runtime.GOOS)
+}
+
+func (c *dfConn) Close() error {
+	return nil
+}
+
+func (c *dfConn) echo(dst net.IP, size int, seq int, timeout time.Duration) (bool, int, error) {
+	return false, 0, fmt.Errorf("path MTU discovery is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,53 @@
+package status
+
+import (
+	"testing"
+)
+
+// fakePath fits packets up to mtu, optionally answering larger ones with the
+// next hop MTU like a router sending fragmentation needed
+func fakePath(mtu int, hint bool, probes *int) func(int) (bool, int, error) {
+	return func(size int) (bool, int, error) {
+		*probes++
+		if size <= mtu {
+			return true, 0, nil
+		}
+		if hint {
+			return false, mtu, nil
+		}
+		return false, 0, nil
+	}
+}
+
+func TestSearchMTU(t *testing.T) {
+	cases := []struct {
+		name      string
+		mtu       int
+		hint      bool
+		maxProbes int
+	}{
+		{"full size", 1500, false, 2},
+		{"blackhole", 1420, false, 12},
+		{"fragmentation needed", 1420, true, 3},
+		{"minimum", MinMTU, false, 12},
+	}
+
+	for _, c := range cases {
+		probes := 0
+		mtu, err := SearchMTU(MinMTU, 1500, fakePath(c.mtu, c.hint, &probes))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if mtu != c.mtu {
+			t.Fatalf("%s: expected MTU %d but got %d", c.name, c.mtu, mtu)
+		}
+		if probes > c.maxProbes {
+			t.Fatalf("%s: expected at most %d probes but sent %d", c.name, c.maxProbes, probes)
+		}
+	}
+
+	probes := 0
+	if _, err := SearchMTU(MinMTU, 1500, fakePath(0, false, &probes)); err == nil {
+		t.Fatal("Expected an error for an unreachable target")
+	}
+}
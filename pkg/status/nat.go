@@ -0,0 +1,281 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/stun"
+)
+
+const (
+	// BehaviorNone means the mapped address is one of this host's own addresses
+	BehaviorNone = "none"
+	// BehaviorEndpointIndependent means the same mapping or filter applies to every destination
+	BehaviorEndpointIndependent = "endpoint_independent"
+	// BehaviorAddressDependent means the mapping or filter depends on the destination address
+	BehaviorAddressDependent = "address_dependent"
+	// BehaviorAddressPortDependent means the mapping or filter depends on the destination address and port
+	BehaviorAddressPortDependent = "address_port_dependent"
+	// BehaviorDependent means mappings differ between servers but none supported
+	// RFC 5780 to tell whether by address or by address and port
+	BehaviorDependent = "dependent"
+	// BehaviorUnknown means the behavior could not be tested
+	BehaviorUnknown = "unknown"
+)
+
+var (
+	DefaultSTUNServers = []string{"stun.stunprotocol.org:3478", "stun.l.google.com:19302"}
+	DefaultSTUNTimeout = 15 * time.Second
+)
+
+// Binder runs a single STUN binding transaction, see stun.Client.Binding
+type Binder func(ctx context.Context, server net.Addr, change uint32) (*stun.Response, error)
+
+// DetectNAT finds the public mapped address through STUNServers and classifies
+// the NAT's mapping and filtering behavior. Every request is sent from the same
+// local socket so mappings can be compared, except the filtering tests which
+// need a socket whose filter the mapping tests have not opened
+func (s *Status) DetectNAT() *models.NATReport {
+	report := &models.NATReport{
+		Mapping:   BehaviorUnknown,
+		Filtering: BehaviorUnknown,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.STUNTimeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.TermChannel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	defer conn.Close()
+	report.LocalAddress = conn.LocalAddr().String()
+
+	for _, server := range s.STUNServers {
+		sr := &models.STUNReport{Server: server}
+		report.Servers = append(report.Servers, sr)
+
+		addr, err := resolveUDP4(ctx, server)
+		if err != nil {
+			sr.Error = err.Error()
+			continue
+		}
+		sr.Address = addr.String()
+	}
+
+	filterConn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	defer filterConn.Close()
+
+	ClassifyNAT(ctx, stun.NewClient(conn).Binding, stun.NewClient(filterConn).Binding, report, isLocalIP)
+
+	return report
+}
+
+// ClassifyNAT runs RFC 5780 behavior discovery against the resolved servers in
+// report, mapping tests through bind and filtering tests through filterBind which
+// must send from a different socket. Both are tested against the first server
+// which reports an alternate address, without one mapping can only be compared
+// across servers and filtering is unknown
+func ClassifyNAT(ctx context.Context, bind Binder, filterBind Binder, report *models.NATReport, isLocal func(net.IP) bool) {
+	var primary *net.UDPAddr
+	var first, x1, other *net.UDPAddr
+	mapped := make([]*net.UDPAddr, 0, len(report.Servers))
+
+	for _, sr := range report.Servers {
+		if sr.Error != "" {
+			continue
+		}
+		addr, err := net.ResolveUDPAddr("udp4", sr.Address)
+		if err != nil {
+			sr.Error = err.Error()
+			continue
+		}
+
+		resp, err := bind(ctx, addr, 0)
+		if err != nil {
+			sr.Error = err.Error()
+			continue
+		}
+
+		sr.Mapped = resp.Mapped.String()
+		sr.RTT = resp.RTT
+		if first == nil {
+			first = resp.Mapped
+		}
+		if resp.Other != nil {
+			sr.Other = resp.Other.String()
+			if primary == nil {
+				primary, x1, other = addr, resp.Mapped, resp.Other
+			}
+		}
+		mapped = append(mapped, resp.Mapped)
+	}
+
+	if first == nil {
+		report.Error = "no STUN server responded"
+		report.Type, report.Console = NATType(report.Mapping, report.Filtering)
+		return
+	}
+	if x1 == nil {
+		x1 = first
+	}
+
+	report.MappedAddress = x1.String()
+	localPort := 0
+	if _, port, err := net.SplitHostPort(report.LocalAddress); err == nil {
+		localPort, _ = strconv.Atoi(port)
+	}
+	report.PortPreserved = x1.Port == localPort
+
+	switch {
+	case isLocal(x1.IP) && report.PortPreserved:
+		report.Mapping = BehaviorNone
+	case primary != nil:
+		report.Mapping = testMapping(ctx, bind, report, primary, other, x1)
+	case len(mapped) > 1:
+		report.Mapping = BehaviorEndpointIndependent
+		for _, m := range mapped[1:] {
+			if !sameAddr(m, mapped[0]) {
+				report.Mapping = BehaviorDependent
+			}
+		}
+	}
+
+	if primary != nil {
+		report.Filtering = testFiltering(ctx, filterBind, report, primary)
+	}
+
+	report.Type, report.Console = NATType(report.Mapping, report.Filtering)
+}
+
+// testMapping sends from the same socket to the alternate address, then to the
+// alternate address and port, and compares the mappings (RFC 5780 4.3)
+func testMapping(ctx context.Context, bind Binder, report *models.NATReport, primary *net.UDPAddr, other *net.UDPAddr, x1 *net.UDPAddr) string {
+	r2, err := bind(ctx, &net.UDPAddr{IP: other.IP, Port: primary.Port}, 0)
+	if err != nil {
+		report.Error = fmt.Sprintf("mapping test: %v", err)
+		return BehaviorUnknown
+	}
+	if sameAddr(r2.Mapped, x1) {
+		return BehaviorEndpointIndependent
+	}
+
+	r3, err := bind(ctx, other, 0)
+	if err != nil {
+		report.Error = fmt.Sprintf("mapping test: %v", err)
+		return BehaviorUnknown
+	}
+	if sameAddr(r3.Mapped, r2.Mapped) {
+		return BehaviorAddressDependent
+	}
+	return BehaviorAddressPortDependent
+}
+
+// testFiltering opens a mapping to the server then asks it to respond from
+// its alternate address and port, then from its alternate port only, and checks
+// which responses the NAT lets through (RFC 5780 4.4)
+func testFiltering(ctx context.Context, bind Binder, report *models.NATReport, primary *net.UDPAddr) string {
+	if _, err := bind(ctx, primary, 0); err != nil {
+		report.Error = fmt.Sprintf("filtering test: %v", err)
+		return BehaviorUnknown
+	}
+
+	_, err := bind(ctx, primary, stun.ChangeIP|stun.ChangePort)
+	if err == nil {
+		return BehaviorEndpointIndependent
+	}
+	if !errors.Is(err, stun.ErrTimeout) {
+		report.Error = fmt.Sprintf("filtering test: %v", err)
+		return BehaviorUnknown
+	}
+
+	_, err = bind(ctx, primary, stun.ChangePort)
+	if err == nil {
+		return BehaviorAddressDependent
+	}
+	if !errors.Is(err, stun.ErrTimeout) {
+		report.Error = fmt.Sprintf("filtering test: %v", err)
+		return BehaviorUnknown
+	}
+	return BehaviorAddressPortDependent
+}
+
+// NATType returns the classic (RFC 3489) name of a mapping and filtering
+// combination and the open, moderate or strict rating game consoles show
+func NATType(mapping string, filtering string) (string, string) {
+	switch mapping {
+	case BehaviorNone:
+		return "no NAT", "open"
+	case BehaviorEndpointIndependent:
+		switch filtering {
+		case BehaviorEndpointIndependent:
+			return "full cone", "open"
+		case BehaviorAddressDependent:
+			return "restricted cone", "moderate"
+		case BehaviorAddressPortDependent:
+			return "port restricted cone", "moderate"
+		default:
+			return "cone", "moderate"
+		}
+	case BehaviorAddressDependent, BehaviorAddressPortDependent, BehaviorDependent:
+		return "symmetric", "strict"
+	default:
+		return BehaviorUnknown, BehaviorUnknown
+	}
+}
+
+func sameAddr(a *net.UDPAddr, b *net.UDPAddr) bool {
+	return a.IP.Equal(b.IP) && a.Port == b.Port
+}
+
+func resolveUDP4(ctx context.Context, hostport string) (*net.UDPAddr, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in %q", hostport)
+	}
+
+	if ip := net.ParseIP(host).To4(); ip != nil {
+		return &net.UDPAddr{IP: ip, Port: p}, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+	if err != nil {
+		return nil, err
+	}
+	return &net.UDPAddr{IP: ips[0], Port: p}, nil
+}
+
+func isLocalIP(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
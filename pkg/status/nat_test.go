@@ -0,0 +1,141 @@
+package status
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+	"github.com/asciifaceman/gomo/pkg/stun"
+)
+
+var (
+	natPrimary = &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 3478}
+	natOther   = &net.UDPAddr{IP: net.IPv4(198, 51, 100, 2), Port: 3479}
+	natPlain   = &net.UDPAddr{IP: net.IPv4(203, 0, 113, 50), Port: 19302}
+)
+
+// fakeNAT maps and filters bindings like a NAT with the given behaviors in
+// front of an RFC 5780 server at natPrimary/natOther and plain servers elsewhere
+type fakeNAT struct {
+	mapping   string
+	filtering string
+	mappings  map[string]int
+	contacted []*net.UDPAddr
+}
+
+func (n *fakeNAT) bind(ctx context.Context, server net.Addr, change uint32) (*stun.Response, error) {
+	dst := server.(*net.UDPAddr)
+	n.contacted = append(n.contacted, dst)
+
+	key := ""
+	switch n.mapping {
+	case BehaviorAddressDependent:
+		key = dst.IP.String()
+	case BehaviorAddressPortDependent:
+		key = dst.String()
+	}
+	port, ok := n.mappings[key]
+	if !ok {
+		port = 40000 + len(n.mappings)
+		n.mappings[key] = port
+	}
+
+	src := &net.UDPAddr{IP: dst.IP, Port: dst.Port}
+	if change&stun.ChangeIP != 0 {
+		src.IP = natOther.IP
+	}
+	if change&stun.ChangePort != 0 {
+		src.Port = natOther.Port
+	}
+
+	allowed := n.filtering == BehaviorEndpointIndependent
+	for _, c := range n.contacted {
+		if n.filtering == BehaviorAddressDependent && c.IP.Equal(src.IP) {
+			allowed = true
+		}
+		if n.filtering == BehaviorAddressPortDependent && sameAddr(c, src) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, stun.ErrTimeout
+	}
+
+	resp := &stun.Response{Mapped: &net.UDPAddr{IP: net.IPv4(100, 88, 12, 7), Port: port}}
+	if dst.IP.Equal(natPrimary.IP) || dst.IP.Equal(natOther.IP) {
+		resp.Other = natOther
+	}
+	return resp, nil
+}
+
+func natReport(servers ...*net.UDPAddr) *models.NATReport {
+	report := &models.NATReport{
+		LocalAddress: "0.0.0.0:40000",
+		Mapping:      BehaviorUnknown,
+		Filtering:    BehaviorUnknown,
+	}
+	for _, s := range servers {
+		report.Servers = append(report.Servers, &models.STUNReport{Server: s.String(), Address: s.String()})
+	}
+	return report
+}
+
+func TestClassifyNAT(t *testing.T) {
+	notLocal := func(net.IP) bool { return false }
+
+	cases := []struct {
+		mapping   string
+		filtering string
+		expected  string
+	}{
+		{BehaviorEndpointIndependent, BehaviorEndpointIndependent, "full cone"},
+		{BehaviorEndpointIndependent, BehaviorAddressDependent, "restricted cone"},
+		{BehaviorEndpointIndependent, BehaviorAddressPortDependent, "port restricted cone"},
+		{BehaviorAddressDependent, BehaviorAddressPortDependent, "symmetric"},
+		{BehaviorAddressPortDependent, BehaviorAddressPortDependent, "symmetric"},
+	}
+
+	for _, c := range cases {
+		nat := &fakeNAT{mapping: c.mapping, filtering: c.filtering, mappings: make(map[string]int)}
+		fresh := &fakeNAT{mapping: c.mapping, filtering: c.filtering, mappings: make(map[string]int)}
+		report := natReport(natPrimary)
+		ClassifyNAT(context.Background(), nat.bind, fresh.bind, report, notLocal)
+
+		if report.Mapping != c.mapping || report.Filtering != c.filtering || report.Type != c.expected {
+			t.Fatalf("Expected %s mapping, %s filtering (%s) but got %s, %s (%s): %s",
+				c.mapping, c.filtering, c.expected, report.Mapping, report.Filtering, report.Type, report.Error)
+		}
+	}
+
+	// without RFC 5780 support mapping can only be compared across servers
+	nat := &fakeNAT{mapping: BehaviorAddressPortDependent, filtering: BehaviorAddressPortDependent, mappings: make(map[string]int)}
+	report := natReport(natPlain, &net.UDPAddr{IP: net.IPv4(203, 0, 113, 51), Port: 19302})
+	ClassifyNAT(context.Background(), nat.bind, nat.bind, report, notLocal)
+	if report.Mapping != BehaviorDependent || report.Filtering != BehaviorUnknown || report.Console != "strict" {
+		t.Fatalf("Expected dependent mapping with unknown filtering but got %+v", report)
+	}
+	if !report.PortPreserved {
+		t.Fatal("Expected the first mapping to preserve the local port")
+	}
+}
+
+func TestDetectNAT(t *testing.T) {
+	server, err := stun.ListenServer(net.IPv4(127, 0, 0, 1), net.IPv4(127, 0, 0, 2))
+	if err != nil {
+		t.Skipf("Could not listen on two loopback addresses: %v", err)
+	}
+	defer server.Close()
+
+	s := NewStatus(0, 1, nil)
+	defer s.Stop()
+	s.STUNServers = []string{server.Primary.String()}
+
+	report := s.DetectNAT()
+	if report.Error != "" {
+		t.Fatal(report.Error)
+	}
+	if report.Mapping != BehaviorNone || report.Filtering != BehaviorEndpointIndependent {
+		t.Fatalf("Expected no NAT on loopback but got %+v", report)
+	}
+}
@@ -0,0 +1,112 @@
+package status
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/net/icmp"
+)
+
+const (
+	// PingModeAuto uses unprivileged ICMP when the host allows it, else raw sockets
+	PingModeAuto = "auto"
+	// PingModePrivileged uses raw ICMP sockets, requires root or CAP_NET_RAW on linux
+	PingModePrivileged = "privileged"
+	// PingModeUnprivileged uses UDP ICMP datagram sockets, requires
+	// net.ipv4.ping_group_range to include the running group on linux
+	PingModeUnprivileged = "unprivileged"
+)
+
+var (
+	DefaultPingMode = PingModeAuto
+	PingModes       = []string{PingModeAuto, PingModePrivileged, PingModeUnprivileged}
+)
+
+// PingPermissionError is returned when the host does not allow the requested ping mode
+type PingPermissionError struct {
+	Mode string
+	Err  error
+}
+
+func (e *PingPermissionError) Error() string {
+	return fmt.Sprintf("%s ping is not permitted on this host: %v\n%s", e.Mode, e.Err, PingPermissionGuidance(e.Mode))
+}
+
+func (e *PingPermissionError) Unwrap() error {
+	return e.Err
+}
+
+// PingPermissionGuidance returns instructions for allowing the given ping mode on this host
+func PingPermissionGuidance(mode string) string {
+	return pingPermissionGuidance(mode, runtime.GOOS)
+}
+
+func pingPermissionGuidance(mode string, goos string) string {
+	switch goos {
+	case "linux":
+		unprivileged := `To allow unprivileged ping for all groups run:
+    sudo sysctl -w net.ipv4.ping_group_range="0 2147483647"
+and persist it in /etc/sysctl.d/ to survive reboots.`
+		privileged := `To allow privileged ping for gomo without running it as root run:
+    sudo setcap cap_net_raw=+ep $(which gomo)`
+		switch mode {
+		case PingModeUnprivileged:
+			return unprivileged
+		case PingModePrivileged:
+			return privileged
+		default:
+			return unprivileged + "\n" + privileged
+		}
+	case "windows":
+		return "Ping on windows requires running gomo from an elevated (administrator) prompt."
+	default:
+		return "Run gomo as root or use --ping-mode to pick a mode this host allows."
+	}
+}
+
+// DetectPingMode checks which ICMP socket type the host allows for the requested
+// mode and returns whether pingers should run privileged
+func DetectPingMode(mode string) (bool, error) {
+	return detectPingMode(mode, probeICMP)
+}
+
+// detectPingMode is DetectPingMode opening ICMP sockets with probe
+func detectPingMode(mode string, probe func(network string) error) (bool, error) {
+	switch mode {
+	case PingModePrivileged:
+		if err := probe("ip4:icmp"); err != nil {
+			return false, &PingPermissionError{Mode: mode, Err: err}
+		}
+		return true, nil
+	case PingModeUnprivileged:
+		if runtime.GOOS == "windows" {
+			return false, &PingPermissionError{Mode: mode, Err: fmt.Errorf("unprivileged ping is not supported on windows")}
+		}
+		if err := probe("udp4"); err != nil {
+			return false, &PingPermissionError{Mode: mode, Err: err}
+		}
+		return false, nil
+	case PingModeAuto, "":
+		if runtime.GOOS == "windows" {
+			return detectPingMode(PingModePrivileged, probe)
+		}
+		unprivilegedErr := probe("udp4")
+		if unprivilegedErr == nil {
+			return false, nil
+		}
+		if err := probe("ip4:icmp"); err == nil {
+			return true, nil
+		}
+		return false, &PingPermissionError{Mode: PingModeAuto, Err: unprivilegedErr}
+	default:
+		return false, fmt.Errorf("unknown ping mode %q, expected one of %v", mode, PingModes)
+	}
+}
+
+func probeICMP(network string) error {
+	conn, err := icmp.ListenPacket(network, "0.0.0.0")
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
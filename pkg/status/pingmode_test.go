@@ -0,0 +1,100 @@
+package status
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDetectPingMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows only has privileged ping")
+	}
+
+	denied := errors.New("operation not permitted")
+	allow := func(networks ...string) func(string) error {
+		return func(network string) error {
+			for _, n := range networks {
+				if n == network {
+					return nil
+				}
+			}
+			return denied
+		}
+	}
+
+	cases := []struct {
+		name       string
+		mode       string
+		probe      func(string) error
+		privileged bool
+		err        bool
+	}{
+		{"auto prefers unprivileged", PingModeAuto, allow("udp4", "ip4:icmp"), false, false},
+		{"auto falls back to raw", PingModeAuto, allow("ip4:icmp"), true, false},
+		{"auto neither", PingModeAuto, allow(), false, true},
+		{"empty is auto", "", allow("udp4"), false, false},
+		{"privileged", PingModePrivileged, allow("ip4:icmp"), true, false},
+		{"privileged denied", PingModePrivileged, allow("udp4"), false, true},
+		{"unprivileged", PingModeUnprivileged, allow("udp4"), false, false},
+		{"unprivileged denied", PingModeUnprivileged, allow("ip4:icmp"), false, true},
+		{"unknown", "raw", allow("udp4", "ip4:icmp"), false, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			privileged, err := detectPingMode(c.mode, c.probe)
+			if privileged != c.privileged || (err != nil) != c.err {
+				t.Fatalf("Expected privileged %v and error %v but got %v, %v", c.privileged, c.err, privileged, err)
+			}
+			var permErr *PingPermissionError
+			if c.err && c.mode != "raw" && !errors.As(err, &permErr) {
+				t.Fatalf("Expected a PingPermissionError but got %v", err)
+			}
+		})
+	}
+}
+
+func TestPingPermissionError(t *testing.T) {
+	err := &PingPermissionError{Mode: PingModePrivileged, Err: errors.New("denied")}
+	msg := err.Error()
+	if !strings.Contains(msg, "denied") {
+		t.Errorf("Expected the cause in %q", msg)
+	}
+	if !strings.Contains(msg, PingPermissionGuidance(PingModePrivileged)) {
+		t.Errorf("Expected this host's guidance in %q", msg)
+	}
+}
+
+func TestPingPermissionGuidance(t *testing.T) {
+	cases := []struct {
+		name     string
+		mode     string
+		goos     string
+		expected []string
+		missing  []string
+	}{
+		{"unprivileged on linux", PingModeUnprivileged, "linux", []string{"ping_group_range"}, []string{"setcap"}},
+		{"privileged on linux", PingModePrivileged, "linux", []string{"setcap cap_net_raw"}, []string{"ping_group_range"}},
+		{"auto on linux", PingModeAuto, "linux", []string{"ping_group_range", "setcap cap_net_raw"}, nil},
+		{"windows", PingModePrivileged, "windows", []string{"administrator"}, []string{"setcap", "ping_group_range"}},
+		{"other", PingModeAuto, "darwin", []string{"--ping-mode"}, []string{"setcap", "ping_group_range"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msg := pingPermissionGuidance(c.mode, c.goos)
+			for _, s := range c.expected {
+				if !strings.Contains(msg, s) {
+					t.Errorf("Expected %q in %q", s, msg)
+				}
+			}
+			for _, s := range c.missing {
+				if strings.Contains(msg, s) {
+					t.Errorf("Expected no %q in %q", s, msg)
+				}
+			}
+		})
+	}
+}
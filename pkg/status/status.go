@@ -3,6 +3,9 @@
 package status
 
 import (
+	"context"
+	"errors"
+	"net"
 	"os"
 	"os/signal"
 	"sync"
@@ -16,67 +19,477 @@ import (
 var (
 	DefaultPingHosts   = []string{"www.google.com", "github.com"}
 	DefaultWorkerCount = 2
+	DefaultPingCount   = 5
+	DefaultPingTimeout = 15 * time.Second
 )
 
 type Status struct {
 	PingCount       int
+	PingTimeout     time.Duration
+	Privileged      bool
+	Workers         int
 	TermChannel     chan interface{}
 	ErrorChannel    chan error
 	PingStatChannel chan *probing.Statistics
 	Signals         chan os.Signal
 	PingHosts       []string
+	Families        []string
+	Gateway         string
+	DNSNames        []string
+	DNSResolvers    []DNSResolver
+	DNSCount        int
+	DNSTimeout      time.Duration
+	TCPTargets      []string
+	TCPTimeout      time.Duration
+	HTTPTargets     []string
+	HTTPTimeout     time.Duration
+	MTUTargets      []string
+	MTUMax          int
+	MTUTimeout      time.Duration
+	MTURetries      int
+	EchoURL         string
+	STUNServers     []string
+	STUNTimeout     time.Duration
+	WANs            []WAN
+
+	HTTPExpectedCodes []int
+
+	stopOnce    sync.Once
+	streakMu    sync.Mutex
+	lossStreaks map[string]int
 }
 
-func NewStatus(pingCount int, pingHosts []string) *Status {
+// NewStatus returns a Status which will ping each of pingHosts pingCount times
+// spread across workers concurrent pingers. A workers value below 1 uses DefaultWorkerCount
+func NewStatus(pingCount int, workers int, pingHosts []string) *Status {
+	if workers < 1 {
+		workers = DefaultWorkerCount
+	}
+
 	s := &Status{
-		TermChannel:     make(chan interface{}, 1),
+		TermChannel:     make(chan interface{}),
 		ErrorChannel:    make(chan error, 1),
 		Signals:         make(chan os.Signal, 1),
 		PingStatChannel: make(chan *probing.Statistics, len(pingHosts)),
 		PingCount:       pingCount,
+		PingTimeout:     DefaultPingTimeout,
+		Workers:         workers,
 		PingHosts:       pingHosts,
+		DNSCount:        DefaultDNSCount,
+		DNSTimeout:      DefaultDNSTimeout,
+		TCPTimeout:      DefaultTCPTimeout,
+		HTTPTimeout:     DefaultHTTPTimeout,
+		MTUMax:          DefaultMTUMax,
+		MTUTimeout:      DefaultMTUTimeout,
+		MTURetries:      DefaultMTURetries,
+		STUNTimeout:     DefaultSTUNTimeout,
+
+		HTTPExpectedCodes: DefaultHTTPExpectedCodes,
+
+		lossStreaks: make(map[string]int),
 	}
 
 	signal.Notify(s.Signals, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 
+	go func() {
+		select {
+		case <-s.Signals:
+			s.Stop()
+		case <-s.TermChannel:
+		}
+	}()
+
 	return s
 }
 
-// PingAsync ...
-func (s *Status) PingAsync(wg *sync.WaitGroup, work chan string, ret chan *models.PingReportReturn) {
+// Stop interrupts any running pingers. A stopped Status cannot be run again
+func (s *Status) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.TermChannel)
+	})
+}
+
+// LoadPinger returns a Status pinging the same targets the same way as s but
+// carrying loss streaks of its own, so pings under deliberate load, ex. during
+// a bufferbloat test, don't count against the streaks of s. It is stopped
+// along with s and must not be stopped itself
+func (s *Status) LoadPinger() *Status {
+	return &Status{
+		PingCount:   s.PingCount,
+		PingTimeout: s.PingTimeout,
+		Privileged:  s.Privileged,
+		Workers:     s.Workers,
+		TermChannel: s.TermChannel,
+		PingHosts:   s.PingHosts,
+		Families:    s.Families,
+		DNSTimeout:  s.DNSTimeout,
+
+		lossStreaks: make(map[string]int),
+	}
+}
+
+// Run runs every configured per poll check concurrently and returns their
+// combined results. The checks of things which rarely change are left to
+// Discover
+func (s *Status) Run() *models.StatusReport {
+	var wg sync.WaitGroup
+	report := &models.StatusReport{Start: time.Now()}
+
+	if len(s.PingHosts) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Pings = s.Ping()
+		}()
+	}
+
+	if s.Gateway != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Gateway = s.PingGateway()
+		}()
+	}
+
+	if len(s.DNSNames) > 0 && len(s.DNSResolvers) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.DNS = s.Resolve()
+		}()
+	}
+
+	if len(s.TCPTargets) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.TCP = s.Connect()
+		}()
+	}
+
+	if len(s.HTTPTargets) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.HTTP = s.Fetch()
+		}()
+	}
+
+	if len(s.WANs) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.WANs = s.CompareWANs()
+		}()
+	}
+
+	wg.Wait()
+
+	if report.Gateway != nil && len(report.Pings) > 0 {
+		report.Split = Isolate(report.Gateway, gatewayFamily(report.Pings))
+	}
+
+	if len(s.Families) > 0 {
+		report.Families = CompareFamilies(s.Families, report)
+	}
+
+	return report
+}
+
+// Discover runs the checks of things which rarely change and are slow or
+// costly to check, path MTU, the public address and the NAT type,
+// concurrently and returns
+// their combined results. It is meant to run far less often than Run
+func (s *Status) Discover() *models.StatusReport {
+	var wg sync.WaitGroup
+	report := &models.StatusReport{Start: time.Now()}
+
+	if len(s.MTUTargets) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.MTU = s.DiscoverMTU()
+		}()
+	}
+
+	if s.EchoURL != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Echo = s.PublicAddress()
+		}()
+	}
+
+	if len(s.STUNServers) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.NAT = s.DetectNAT()
+		}()
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+// Ping fans PingHosts out to Workers pingers and returns one report per host,
+// and per address family if Families is set, in the same order as PingHosts
+func (s *Status) Ping() []*models.PingReportReturn {
+	return s.pingProbes(s.probes(s.PingHosts))
+}
+
+// pingProbes fans probes out to Workers pingers and returns one report per
+// probe in the same order. Every probe must share the same source
+func (s *Status) pingProbes(probes []Probe) []*models.PingReportReturn {
+	var wg sync.WaitGroup
+
+	work := make(chan Probe, len(probes))
+	ret := make(chan *models.PingReportReturn, len(probes))
+
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go s.PingAsync(&wg, work, ret)
+	}
+
+	for _, probe := range probes {
+		work <- probe
+	}
+	close(work)
+
+	wg.Wait()
+	close(ret)
+
+	byProbe := make(map[Probe]*models.PingReportReturn, len(probes))
+	for result := range ret {
+		byProbe[Probe{Target: result.Hostname, Family: result.Family}] = result
+	}
+
+	reports := make([]*models.PingReportReturn, 0, len(probes))
+	for _, probe := range probes {
+		if result, ok := byProbe[Probe{Target: probe.Target, Family: probe.Family}]; ok {
+			reports = append(reports, result)
+		}
+	}
+
+	return reports
+}
+
+// PingAsync pings each Probe received on work until work is closed
+func (s *Status) PingAsync(wg *sync.WaitGroup, work chan Probe, ret chan *models.PingReportReturn) {
 	defer wg.Done()
-	for hostname := range work {
-		result := &models.PingReportReturn{}
-		pinger, err := probing.NewPinger(hostname)
+	for probe := range work {
+		hostname := probe.Target
+		result := &models.PingReportReturn{Hostname: hostname, Family: probe.Family}
+		if probe.Device != "" {
+			ret <- s.pingDeviceReport(probe, result)
+			continue
+		}
+
+		pinger, err := s.newPinger(probe)
 		if err != nil {
 			result.Error = err
+			result.NoRecord = IsNoRecord(err)
+			result.Body = s.failedReport(probe)
 			ret <- result
 			continue
 		}
 		pinger.Count = s.PingCount
-		pinger.Timeout = 15 * time.Second
+		pinger.Timeout = s.PingTimeout
+		pinger.SetPrivileged(s.Privileged)
+
+		var mu sync.Mutex
+		sent := make([]int, 0, s.PingCount)
+		received := make(map[int]bool, s.PingCount)
+		pinger.OnSend = func(pkt *probing.Packet) {
+			mu.Lock()
+			sent = append(sent, pkt.Seq)
+			mu.Unlock()
+		}
+		pinger.OnRecv = func(pkt *probing.Packet) {
+			mu.Lock()
+			received[pkt.Seq] = true
+			mu.Unlock()
+		}
 
+		done := make(chan interface{})
 		go func() {
-			for range s.Signals {
+			select {
+			case <-s.TermChannel:
 				pinger.Stop()
+			case <-done:
 			}
 		}()
 
 		err = pinger.Run()
+		close(done)
 		if err != nil {
 			result.Error = err
+			result.Body = s.failedReport(probe)
 			ret <- result
 			continue
 		}
 
-		stats := pinger.Statistics()
+		result.Body = Summarize(hostname, pinger.Statistics())
+		mu.Lock()
+		result.Body.LossStreak, result.Body.LossStreakMax = s.trackLoss(probe.key(), LossRuns(sent, received))
+		mu.Unlock()
+		ret <- result
+	}
+}
 
-		result.Body = &models.PingReport{
-			Hostname:        stats.Addr,
-			PacketsSent:     stats.PacketsSent,
-			PacketLoss:      stats.PacketLoss,
-			AvgResponseTime: stats.AvgRtt,
+// pingDeviceReport fills in result by pinging through probe's Device. Pings
+// which can't be pinned to the device are reported without a body rather
+// than as loss
+func (s *Status) pingDeviceReport(probe Probe, result *models.PingReportReturn) *models.PingReportReturn {
+	stats, run, err := s.pingDevice(probe)
+	if err != nil {
+		result.Error = err
+		result.NoRecord = IsNoRecord(err)
+		if !errors.Is(err, ErrPingDevice) {
+			result.Body = s.failedReport(probe)
 		}
-		ret <- result
+		return result
 	}
+
+	result.Body = Summarize(probe.Target, stats)
+	result.Body.LossStreak, result.Body.LossStreakMax = s.trackLoss(probe.key(), run)
+	return result
+}
+
+// newPinger returns a pinger for probe, resolving the target within the probe's
+// address family and binding it to the probe's source if it has them
+func (s *Status) newPinger(probe Probe) (*probing.Pinger, error) {
+	if probe.Family == "" {
+		return probing.NewPinger(probe.Target)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.DNSTimeout)
+	defer cancel()
+
+	addrs, err := lookupFamily(ctx, probe.Family, probe.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	pinger := probing.New(probe.Target)
+	pinger.SetNetwork(probe.Family)
+	pinger.SetIPAddr(&net.IPAddr{IP: addrs[0]})
+	pinger.Source = probe.Source
+
+	return pinger, nil
+}
+
+// LossRun describes the runs of consecutive lost packets within a single check
+type LossRun struct {
+	Sent     int
+	Leading  int
+	Longest  int
+	Trailing int
+}
+
+// LossRuns finds the leading, longest and trailing runs of lost packets given the
+// sequence numbers sent in order and the set of sequence numbers received
+func LossRuns(sent []int, received map[int]bool) LossRun {
+	run := LossRun{Sent: len(sent)}
+	leading := true
+	current := 0
+
+	for _, seq := range sent {
+		if received[seq] {
+			if leading {
+				run.Leading = current
+				leading = false
+			}
+			current = 0
+			continue
+		}
+		current++
+		if current > run.Longest {
+			run.Longest = current
+		}
+	}
+
+	if leading {
+		run.Leading = current
+	}
+	run.Trailing = current
+
+	return run
+}
+
+// failedReport is the PingReport of a check that failed outright, every packet
+// that would have been sent counts as lost
+func (s *Status) failedReport(probe Probe) *models.PingReport {
+	report := &models.PingReport{
+		Hostname:    probe.Target,
+		PacketsSent: s.PingCount,
+		PacketLoss:  100,
+	}
+	run := LossRun{Sent: s.PingCount, Leading: s.PingCount, Longest: s.PingCount, Trailing: s.PingCount}
+	report.LossStreak, report.LossStreakMax = s.trackLoss(probe.key(), run)
+	return report
+}
+
+// trackLoss carries consecutive packet loss across checks to the same host, or
+// host and family, identified by key and returns the current streak and the
+// longest streak seen during this check
+func (s *Status) trackLoss(key string, run LossRun) (int, int) {
+	s.streakMu.Lock()
+	defer s.streakMu.Unlock()
+
+	carry := s.lossStreaks[key]
+
+	if run.Leading == run.Sent {
+		current := carry + run.Sent
+		s.lossStreaks[key] = current
+		return current, current
+	}
+
+	longest := run.Longest
+	if carry+run.Leading > longest {
+		longest = carry + run.Leading
+	}
+	s.lossStreaks[key] = run.Trailing
+
+	return run.Trailing, longest
+}
+
+// Summarize converts pinger statistics into a PingReport
+func Summarize(hostname string, stats *probing.Statistics) *models.PingReport {
+	report := &models.PingReport{
+		Hostname:        hostname,
+		PacketsSent:     stats.PacketsSent,
+		PacketsRecv:     stats.PacketsRecv,
+		PacketLoss:      stats.PacketLoss,
+		MinResponseTime: stats.MinRtt,
+		AvgResponseTime: stats.AvgRtt,
+		MaxResponseTime: stats.MaxRtt,
+		Jitter:          Jitter(stats.Rtts),
+		Rtts:            stats.Rtts,
+	}
+
+	if stats.IPAddr != nil {
+		report.IPAddr = stats.IPAddr.String()
+	}
+
+	return report
+}
+
+// Jitter returns the mean absolute difference between consecutive round trip times
+func Jitter(rtts []time.Duration) time.Duration {
+	if len(rtts) < 2 {
+		return 0
+	}
+
+	var total time.Duration
+	for i := 1; i < len(rtts); i++ {
+		diff := rtts[i] - rtts[i-1]
+		if diff < 0 {
+			diff = -diff
+		}
+		total += diff
+	}
+
+	return total / time.Duration(len(rtts)-1)
 }
@@ -0,0 +1,76 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+)
+
+func TestJitter(t *testing.T) {
+	rtts := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		15 * time.Millisecond,
+		15 * time.Millisecond,
+	}
+
+	// |20-10| + |15-20| + |15-15| = 15ms over 3 intervals
+	if j := Jitter(rtts); j != 5*time.Millisecond {
+		t.Fatalf("Expected jitter of 5ms but got %s", j)
+	}
+
+	if j := Jitter(rtts[:1]); j != 0 {
+		t.Fatalf("Expected jitter of 0 for a single sample but got %s", j)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stats := &probing.Statistics{
+		PacketsSent: 4,
+		PacketsRecv: 3,
+		PacketLoss:  25,
+		Rtts:        []time.Duration{10 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond},
+		MinRtt:      10 * time.Millisecond,
+		AvgRtt:      20 * time.Millisecond,
+		MaxRtt:      30 * time.Millisecond,
+	}
+
+	report := Summarize("example.com", stats)
+	if report.Hostname != "example.com" {
+		t.Fatalf("Expected hostname example.com but got %s", report.Hostname)
+	}
+	if report.PacketLoss != 25 || report.PacketsRecv != 3 {
+		t.Fatalf("Expected 25%% loss with 3 received but got %f%% with %d", report.PacketLoss, report.PacketsRecv)
+	}
+	if report.Jitter != 15*time.Millisecond {
+		t.Fatalf("Expected jitter of 15ms but got %s", report.Jitter)
+	}
+}
+
+func TestLossStreaks(t *testing.T) {
+	s := NewStatus(5, 1, nil)
+	defer s.Stop()
+
+	// lost 0, 2, 3 and 4 of the first check
+	run := LossRuns([]int{0, 1, 2, 3, 4}, map[int]bool{1: true})
+	if run.Leading != 1 || run.Longest != 3 || run.Trailing != 3 {
+		t.Fatalf("Expected leading 1, longest 3, trailing 3 but got %+v", run)
+	}
+	current, longest := s.trackLoss("example.com", run)
+	if current != 3 || longest != 3 {
+		t.Fatalf("Expected current 3, longest 3 but got %d, %d", current, longest)
+	}
+
+	// nothing came back, the streak carries over
+	current, longest = s.trackLoss("example.com", LossRuns([]int{5, 6}, map[int]bool{}))
+	if current != 5 || longest != 5 {
+		t.Fatalf("Expected current 5, longest 5 but got %d, %d", current, longest)
+	}
+
+	// lost 7 then recovered
+	current, longest = s.trackLoss("example.com", LossRuns([]int{7, 8, 9}, map[int]bool{8: true, 9: true}))
+	if current != 0 || longest != 6 {
+		t.Fatalf("Expected current 0, longest 6 but got %d, %d", current, longest)
+	}
+}
@@ -0,0 +1,121 @@
+package status
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+var (
+	DefaultTCPTargets = []string{"www.google.com:443", "github.com:443"}
+	DefaultTCPTimeout = 5 * time.Second
+)
+
+// Connect fans TCPTargets out to Workers dialers and returns one report per
+// target, and per address family if Families is set, in the same order as TCPTargets
+func (s *Status) Connect() []*models.TCPReport {
+	return s.connectProbes(s.probes(s.TCPTargets))
+}
+
+// connectProbes fans probes out to Workers dialers and returns one report per
+// probe in the same order. Every probe must share the same source
+func (s *Status) connectProbes(probes []Probe) []*models.TCPReport {
+	var wg sync.WaitGroup
+
+	work := make(chan Probe, len(probes))
+	ret := make(chan *models.TCPReport, len(probes))
+
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go s.ConnectAsync(&wg, work, ret)
+	}
+
+	for _, probe := range probes {
+		work <- probe
+	}
+	close(work)
+
+	wg.Wait()
+	close(ret)
+
+	byProbe := make(map[Probe]*models.TCPReport, len(probes))
+	for report := range ret {
+		byProbe[Probe{Target: report.Target, Family: report.Family}] = report
+	}
+
+	reports := make([]*models.TCPReport, 0, len(probes))
+	for _, probe := range probes {
+		if report, ok := byProbe[Probe{Target: probe.Target, Family: probe.Family}]; ok {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports
+}
+
+// ConnectAsync dials each host:port Probe received on work until work is closed
+func (s *Status) ConnectAsync(wg *sync.WaitGroup, work chan Probe, ret chan *models.TCPReport) {
+	defer wg.Done()
+	for probe := range work {
+		ret <- s.connect(probe)
+	}
+}
+
+func (s *Status) connect(probe Probe) *models.TCPReport {
+	report := &models.TCPReport{Target: probe.Target, Family: probe.Family}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.TCPTimeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.TermChannel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(probe.Target)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	start := time.Now()
+
+	addrs, err := lookupFamily(ctx, probe.Family, host)
+	if net.ParseIP(host) == nil {
+		report.Phases.DNS = time.Since(start)
+	}
+	if err != nil {
+		report.Error = err.Error()
+		report.NoRecord = IsNoRecord(err)
+		report.Phases.Total = time.Since(start)
+		return report
+	}
+
+	report.Address = net.JoinHostPort(addrs[0].String(), port)
+
+	dialer := net.Dialer{}
+	if probe.Source != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(probe.Source)}
+	}
+	if probe.Device != "" {
+		dialer.Control = bindToDevice(probe.Device)
+	}
+	connectStart := time.Now()
+	conn, err := dialer.DialContext(ctx, network("tcp", probe.Family), report.Address)
+	report.Phases.Connect = time.Since(connectStart)
+	report.Phases.Total = time.Since(start)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	conn.Close()
+
+	report.Up = true
+	return report
+}
@@ -0,0 +1,192 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+// WAN is an uplink probed separately from the default route by binding every
+// probe to its Source, either a local address or an interface name. Probes of
+// an interface are also pinned to it, pings included
+type WAN struct {
+	Name       string
+	Source     string
+	PingHosts  []string
+	TCPTargets []string
+}
+
+// ParseWAN parses a name=source WAN definition, ex. backup=eth1 or
+// cellular=192.168.12.50
+func ParseWAN(spec string) (WAN, error) {
+	name, source, ok := strings.Cut(spec, "=")
+	name, source = strings.TrimSpace(name), strings.TrimSpace(source)
+	if !ok || name == "" || source == "" {
+		return WAN{}, fmt.Errorf("invalid WAN %q, expected name=address or name=interface", spec)
+	}
+	return WAN{Name: name, Source: source}, nil
+}
+
+// GroupTargets parses name=target pairs into the targets of each named WAN,
+// keeping the order they were given in
+func GroupTargets(specs []string) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	for _, spec := range specs {
+		name, target, ok := strings.Cut(spec, "=")
+		name, target = strings.TrimSpace(name), strings.TrimSpace(target)
+		if !ok || name == "" || target == "" {
+			return nil, fmt.Errorf("invalid WAN target %q, expected name=target", spec)
+		}
+		groups[name] = append(groups[name], target)
+	}
+	return groups, nil
+}
+
+// SourceAddress resolves a WAN source to the local address to bind to, and the
+// interface to pin sockets to if the source names one. An interface's first
+// IPv4 address is preferred over IPv6
+func SourceAddress(source string) (net.IP, string, error) {
+	if ip := net.ParseIP(source); ip != nil {
+		return ip, "", nil
+	}
+
+	iface, err := net.InterfaceByName(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s is neither an address nor an interface: %w", source, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, iface.Name, nil
+		}
+		if fallback == nil {
+			fallback = ipNet.IP
+		}
+	}
+	if fallback == nil {
+		return nil, "", fmt.Errorf("interface %s has no usable address", source)
+	}
+
+	return fallback, iface.Name, nil
+}
+
+// CompareWANs probes every WAN concurrently and returns one report per WAN in
+// the same order as WANs
+func (s *Status) CompareWANs() []*models.WANReport {
+	var wg sync.WaitGroup
+
+	reports := make([]*models.WANReport, len(s.WANs))
+	for i, wan := range s.WANs {
+		wg.Add(1)
+		go func(i int, wan WAN) {
+			defer wg.Done()
+			reports[i] = s.probeWAN(wan)
+		}(i, wan)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+func (s *Status) probeWAN(wan WAN) *models.WANReport {
+	report := &models.WANReport{Name: wan.Name, Source: wan.Source}
+
+	ip, device, err := SourceAddress(wan.Source)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Address = ip.String()
+
+	family := FamilyIPv6
+	if ip.To4() != nil {
+		family = FamilyIPv4
+	}
+	bind := func(targets []string) []Probe {
+		probes := make([]Probe, 0, len(targets))
+		for _, target := range targets {
+			probes = append(probes, Probe{Target: target, Family: family, Source: report.Address, Device: device})
+		}
+		return probes
+	}
+
+	var wg sync.WaitGroup
+	if len(wan.PingHosts) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Pings = s.pingProbes(bind(wan.PingHosts))
+		}()
+	}
+	if len(wan.TCPTargets) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.TCP = s.connectProbes(bind(wan.TCPTargets))
+		}()
+	}
+	wg.Wait()
+
+	SummarizeWAN(report)
+
+	return report
+}
+
+// SummarizeWAN fills in a WAN report's loss, latency, jitter and connect time
+// from its ping and TCP results. Loss is averaged per target so a target
+// which could not be pinged at all counts as total loss
+func SummarizeWAN(report *models.WANReport) {
+	var latency, jitter, connect time.Duration
+	var pinged, answered, connected int
+
+	report.Loss = 0
+	for _, p := range report.Pings {
+		if p.NoRecord || errors.Is(p.Error, ErrPingDevice) {
+			continue
+		}
+		pinged++
+		if p.Body == nil {
+			report.Loss++
+			continue
+		}
+		report.Loss += p.Body.PacketLoss / 100
+		if p.Error == nil && p.Body.PacketsRecv > 0 {
+			answered++
+			latency += p.Body.AvgResponseTime
+			jitter += p.Body.Jitter
+		}
+	}
+
+	for _, t := range report.TCP {
+		if t.Up {
+			connected++
+			connect += t.Phases.Connect
+		}
+	}
+
+	if pinged > 0 {
+		report.Loss /= float64(pinged)
+	}
+	if answered > 0 {
+		report.Latency = latency / time.Duration(answered)
+		report.Jitter = jitter / time.Duration(answered)
+	}
+	if connected > 0 {
+		report.Connect = connect / time.Duration(connected)
+	}
+	report.Up = answered > 0 || connected > 0
+}
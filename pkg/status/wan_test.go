@@ -0,0 +1,118 @@
+package status
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/asciifaceman/gomo/pkg/models"
+)
+
+func TestParseWAN(t *testing.T) {
+	wan, err := ParseWAN("backup=eth1")
+	if err != nil || wan.Name != "backup" || wan.Source != "eth1" {
+		t.Fatalf("Expected backup on eth1 but got %+v, %v", wan, err)
+	}
+
+	for _, spec := range []string{"backup", "=eth1", "backup="} {
+		if _, err := ParseWAN(spec); err == nil {
+			t.Fatalf("Expected an error parsing %q", spec)
+		}
+	}
+
+	groups, err := GroupTargets([]string{"backup=1.1.1.1", "cellular=8.8.8.8", "backup=9.9.9.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups["backup"]) != 2 || groups["backup"][1] != "9.9.9.9" {
+		t.Fatalf("Expected two backup targets in order but got %v", groups["backup"])
+	}
+}
+
+func TestSummarizeWAN(t *testing.T) {
+	report := &models.WANReport{
+		Name: "backup",
+		Pings: []*models.PingReportReturn{
+			fakePing("a", 10, 10, 20*time.Millisecond),
+			fakePing("b", 10, 8, 40*time.Millisecond),
+			{Hostname: "c", NoRecord: true},
+		},
+		TCP: []*models.TCPReport{
+			{Target: "a:443", Up: true, Phases: models.ProbePhases{Connect: 25 * time.Millisecond}},
+			{Target: "b:443"},
+		},
+	}
+
+	SummarizeWAN(report)
+
+	if !report.Up {
+		t.Fatalf("Expected backup up")
+	}
+	if report.Loss != 0.1 {
+		t.Fatalf("Expected 10%% loss averaged across a and b but got %f", report.Loss)
+	}
+	if report.Latency != 30*time.Millisecond || report.Connect != 25*time.Millisecond {
+		t.Fatalf("Expected 30ms latency and 25ms connect but got %s and %s", report.Latency, report.Connect)
+	}
+}
+
+func TestPingDevice(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loopback string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			loopback = iface.Name
+			break
+		}
+	}
+	if loopback == "" {
+		t.Skip("No loopback interface")
+	}
+
+	privileged, err := DetectPingMode(PingModeAuto)
+	if err != nil {
+		t.Skipf("Cannot ping on this host: %v", err)
+	}
+
+	s := &Status{
+		Workers:     1,
+		PingCount:   2,
+		PingTimeout: 3 * time.Second,
+		DNSTimeout:  time.Second,
+		Privileged:  privileged,
+		TermChannel: make(chan interface{}),
+		lossStreaks: make(map[string]int),
+		WANs:        []WAN{{Name: "loopback", Source: loopback, PingHosts: []string{"127.0.0.1"}}},
+	}
+
+	report := s.CompareWANs()[0]
+	if len(report.Pings) != 1 {
+		t.Fatalf("Expected a ping report for the loopback WAN but got %+v", report)
+	}
+	ping := report.Pings[0]
+
+	if runtime.GOOS != "linux" {
+		if !errors.Is(ping.Error, ErrPingDevice) || ping.Body != nil || report.Loss != 0 {
+			t.Fatalf("Expected pings through %s to be unsupported and not counted as loss but got %v, %f", loopback, ping.Error, report.Loss)
+		}
+		return
+	}
+
+	var sysErr *os.SyscallError
+	if errors.As(ping.Error, &sysErr) || errors.Is(ping.Error, syscall.EPERM) || errors.Is(ping.Error, syscall.EACCES) {
+		t.Skipf("Cannot pin ICMP sockets to %s: %v", loopback, ping.Error)
+	}
+	if ping.Error != nil || ping.Body == nil || ping.Body.PacketsRecv != 2 {
+		t.Fatalf("Expected both pings answered through %s but got %+v, %v", loopback, ping.Body, ping.Error)
+	}
+	if !report.Up || report.Loss != 0 {
+		t.Fatalf("Expected the loopback WAN up without loss but got %+v", report)
+	}
+}
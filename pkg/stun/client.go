@@ -0,0 +1,118 @@
+package stun
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+var (
+	// DefaultRTO is the initial retransmission timeout, doubled on every retry
+	DefaultRTO     = 500 * time.Millisecond
+	DefaultRetries = 3
+
+	ErrTimeout = errors.New("no response from STUN server")
+)
+
+// Response is the result of a binding transaction
+type Response struct {
+	Mapped *net.UDPAddr
+	Other  *net.UDPAddr
+	Source net.Addr
+	RTT    time.Duration
+}
+
+// Client runs binding transactions from a single local socket so mapped
+// addresses from different servers can be compared. It is not safe for
+// concurrent use
+type Client struct {
+	Conn    net.PacketConn
+	RTO     time.Duration
+	Retries int
+}
+
+// NewClient returns a Client which sends from conn
+func NewClient(conn net.PacketConn) *Client {
+	return &Client{
+		Conn:    conn,
+		RTO:     DefaultRTO,
+		Retries: DefaultRetries,
+	}
+}
+
+// Binding sends a binding request to server, retransmitting with backoff until
+// a response arrives from any address. change asks an RFC 5780 server to
+// respond from its alternate IP and/or port
+func (c *Client) Binding(ctx context.Context, server net.Addr, change uint32) (*Response, error) {
+	req, err := NewBindingRequest(change)
+	if err != nil {
+		return nil, err
+	}
+	b := req.Marshal()
+
+	buf := make([]byte, 1500)
+	rto := c.RTO
+	start := time.Now()
+
+	for attempt := 0; attempt < c.Retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if _, err := c.Conn.WriteTo(b, server); err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(rto)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		if err := c.Conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+
+		for {
+			n, from, err := c.Conn.ReadFrom(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+
+			// late responses to earlier transactions are ignored
+			resp, err := Parse(buf[:n])
+			if err != nil || resp.TransactionID != req.TransactionID {
+				continue
+			}
+
+			if resp.Type == TypeBindingError {
+				return nil, resp.Error()
+			}
+			if resp.Type != TypeBindingResponse {
+				continue
+			}
+
+			mapped, err := resp.MappedAddress()
+			if err != nil {
+				return nil, err
+			}
+
+			ret := &Response{
+				Mapped: mapped,
+				Source: from,
+				RTT:    time.Since(start),
+			}
+			if other, ok := resp.OtherAddress(); ok {
+				ret.Other = other
+			}
+			return ret, nil
+		}
+
+		rto *= 2
+	}
+
+	return nil, ErrTimeout
+}
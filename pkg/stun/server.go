@@ -0,0 +1,135 @@
+package stun
+
+import (
+	"net"
+	"sync"
+)
+
+// Server is a minimal STUN server. Given an alternate IP it listens on two
+// ports of both addresses and honors CHANGE-REQUEST like an RFC 5780 server
+type Server struct {
+	Primary *net.UDPAddr
+	Other   *net.UDPAddr
+
+	// conns is indexed by [address][port], primary first
+	conns [2][2]*net.UDPConn
+	wg    sync.WaitGroup
+}
+
+// ListenServer starts a Server on primary, and alternate if it is not nil,
+// using ports picked by the OS
+func ListenServer(primary net.IP, alternate net.IP) (*Server, error) {
+	var err error
+	for i := 0; i < 10; i++ {
+		s := &Server{}
+		if err = s.listen(primary, alternate); err == nil {
+			s.serve()
+			return s, nil
+		}
+		s.Close()
+	}
+	return nil, err
+}
+
+func (s *Server) listen(primary net.IP, alternate net.IP) error {
+	var err error
+	for p := 0; p < 2; p++ {
+		s.conns[0][p], err = net.ListenUDP("udp", &net.UDPAddr{IP: primary})
+		if err != nil {
+			return err
+		}
+	}
+	s.Primary = s.conns[0][0].LocalAddr().(*net.UDPAddr)
+
+	if alternate == nil {
+		return nil
+	}
+
+	// the alternate address must listen on the same two ports
+	for p := 0; p < 2; p++ {
+		port := s.conns[0][p].LocalAddr().(*net.UDPAddr).Port
+		s.conns[1][p], err = net.ListenUDP("udp", &net.UDPAddr{IP: alternate, Port: port})
+		if err != nil {
+			return err
+		}
+	}
+	s.Other = s.conns[1][1].LocalAddr().(*net.UDPAddr)
+
+	return nil
+}
+
+func (s *Server) serve() {
+	for a := range s.conns {
+		for p := range s.conns[a] {
+			if s.conns[a][p] == nil {
+				continue
+			}
+			s.wg.Add(1)
+			go s.handle(a, p)
+		}
+	}
+}
+
+func (s *Server) handle(a int, p int) {
+	defer s.wg.Done()
+
+	conn := s.conns[a][p]
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		req, err := Parse(buf[:n])
+		if err != nil || req.Type != TypeBindingRequest {
+			continue
+		}
+
+		resp := &Message{Type: TypeBindingResponse, TransactionID: req.TransactionID}
+
+		ra, rp := a, p
+		if val, ok := req.Get(AttrChangeRequest); ok && len(val) == 4 {
+			if s.Other == nil && (val[3]&byte(ChangeIP|ChangePort)) != 0 {
+				resp.Type = TypeBindingError
+				resp.Add(AttrErrorCode, append([]byte{0, 0, 4, 20}, []byte("Unknown Attribute")...))
+				conn.WriteToUDP(resp.Marshal(), from)
+				continue
+			}
+			if val[3]&byte(ChangeIP) != 0 {
+				ra ^= 1
+			}
+			if val[3]&byte(ChangePort) != 0 {
+				rp ^= 1
+			}
+		}
+		out := s.conns[ra][rp]
+
+		resp.AddAddress(AttrXORMappedAddress, from)
+		resp.AddAddress(AttrMappedAddress, from)
+		resp.AddAddress(AttrResponseOrigin, out.LocalAddr().(*net.UDPAddr))
+		if s.Other != nil {
+			resp.AddAddress(AttrOtherAddress, s.Other)
+		}
+		resp.Add(AttrSoftware, []byte("gomo"))
+
+		out.WriteToUDP(resp.Marshal(), from)
+	}
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	var err error
+	for a := range s.conns {
+		for p := range s.conns[a] {
+			if s.conns[a][p] == nil {
+				continue
+			}
+			if cerr := s.conns[a][p].Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	s.wg.Wait()
+	return err
+}
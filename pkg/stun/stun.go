@@ -0,0 +1,246 @@
+// package stun implements the parts of STUN (RFC 5389) and NAT behavior
+// discovery (RFC 5780) needed to find a mapped address and classify a NAT
+package stun
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	// MagicCookie is the fixed value every RFC 5389 message carries
+	MagicCookie uint32 = 0x2112A442
+
+	HeaderSize = 20
+
+	TypeBindingRequest  uint16 = 0x0001
+	TypeBindingResponse uint16 = 0x0101
+	TypeBindingError    uint16 = 0x0111
+
+	AttrMappedAddress    uint16 = 0x0001
+	AttrChangeRequest    uint16 = 0x0003
+	AttrChangedAddress   uint16 = 0x0005
+	AttrErrorCode        uint16 = 0x0009
+	AttrXORMappedAddress uint16 = 0x0020
+	AttrSoftware         uint16 = 0x8022
+	AttrResponseOrigin   uint16 = 0x802B
+	AttrOtherAddress     uint16 = 0x802C
+
+	// ChangeIP asks the server to respond from its alternate address
+	ChangeIP uint32 = 0x04
+	// ChangePort asks the server to respond from its alternate port
+	ChangePort uint32 = 0x02
+
+	familyIPv4 = 0x01
+	familyIPv6 = 0x02
+)
+
+var (
+	ErrNotSTUN   = errors.New("not a STUN message")
+	ErrNoAddress = errors.New("response carried no mapped address")
+)
+
+// Attribute is a single type-length-value attribute of a Message
+type Attribute struct {
+	Type  uint16
+	Value []byte
+}
+
+// Message is a STUN message
+type Message struct {
+	Type          uint16
+	TransactionID [12]byte
+	Attributes    []Attribute
+}
+
+// NewBindingRequest returns a binding request with a random transaction ID.
+// A non zero change asks an RFC 5780 server to respond from its alternate IP
+// and/or port
+func NewBindingRequest(change uint32) (*Message, error) {
+	m := &Message{Type: TypeBindingRequest}
+	if _, err := rand.Read(m.TransactionID[:]); err != nil {
+		return nil, err
+	}
+
+	if change != 0 {
+		val := make([]byte, 4)
+		binary.BigEndian.PutUint32(val, change)
+		m.Add(AttrChangeRequest, val)
+	}
+
+	return m, nil
+}
+
+// Add appends an attribute
+func (m *Message) Add(t uint16, value []byte) {
+	m.Attributes = append(m.Attributes, Attribute{Type: t, Value: value})
+}
+
+// Get returns the value of the first attribute of type t
+func (m *Message) Get(t uint16) ([]byte, bool) {
+	for _, a := range m.Attributes {
+		if a.Type == t {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Marshal encodes the message, padding every attribute to 4 bytes
+func (m *Message) Marshal() []byte {
+	length := 0
+	for _, a := range m.Attributes {
+		length += 4 + padded(len(a.Value))
+	}
+
+	b := make([]byte, HeaderSize+length)
+	binary.BigEndian.PutUint16(b[0:2], m.Type)
+	binary.BigEndian.PutUint16(b[2:4], uint16(length))
+	binary.BigEndian.PutUint32(b[4:8], MagicCookie)
+	copy(b[8:20], m.TransactionID[:])
+
+	offset := HeaderSize
+	for _, a := range m.Attributes {
+		binary.BigEndian.PutUint16(b[offset:], a.Type)
+		binary.BigEndian.PutUint16(b[offset+2:], uint16(len(a.Value)))
+		copy(b[offset+4:], a.Value)
+		offset += 4 + padded(len(a.Value))
+	}
+
+	return b
+}
+
+// Parse decodes a STUN message
+func Parse(b []byte) (*Message, error) {
+	if len(b) < HeaderSize || b[0]&0xc0 != 0 || binary.BigEndian.Uint32(b[4:8]) != MagicCookie {
+		return nil, ErrNotSTUN
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if length%4 != 0 || len(b) < HeaderSize+length {
+		return nil, fmt.Errorf("truncated STUN message")
+	}
+
+	m := &Message{Type: binary.BigEndian.Uint16(b[0:2])}
+	copy(m.TransactionID[:], b[8:20])
+
+	body := b[HeaderSize : HeaderSize+length]
+	for len(body) >= 4 {
+		t := binary.BigEndian.Uint16(body[0:2])
+		l := int(binary.BigEndian.Uint16(body[2:4]))
+		if len(body) < 4+l {
+			return nil, fmt.Errorf("truncated STUN attribute 0x%04x", t)
+		}
+		m.Add(t, body[4:4+l])
+
+		next := 4 + padded(l)
+		if next > len(body) {
+			break
+		}
+		body = body[next:]
+	}
+
+	return m, nil
+}
+
+// Address decodes an address attribute of type t, undoing the XOR for XOR
+// address types
+func (m *Message) Address(t uint16) (*net.UDPAddr, bool) {
+	val, ok := m.Get(t)
+	if !ok || len(val) < 8 {
+		return nil, false
+	}
+
+	port := binary.BigEndian.Uint16(val[2:4])
+	var ip net.IP
+	switch val[1] {
+	case familyIPv4:
+		ip = net.IP(append([]byte(nil), val[4:8]...))
+	case familyIPv6:
+		if len(val) < 20 {
+			return nil, false
+		}
+		ip = net.IP(append([]byte(nil), val[4:20]...))
+	default:
+		return nil, false
+	}
+
+	if t == AttrXORMappedAddress {
+		port ^= uint16(MagicCookie >> 16)
+		key := make([]byte, 16)
+		binary.BigEndian.PutUint32(key[0:4], MagicCookie)
+		copy(key[4:], m.TransactionID[:])
+		for i := range ip {
+			ip[i] ^= key[i]
+		}
+	}
+
+	return &net.UDPAddr{IP: ip, Port: int(port)}, true
+}
+
+// AddAddress appends an address attribute of type t, applying the XOR for XOR
+// address types
+func (m *Message) AddAddress(t uint16, addr *net.UDPAddr) {
+	ip := addr.IP.To4()
+	family := byte(familyIPv4)
+	if ip == nil {
+		ip = addr.IP.To16()
+		family = familyIPv6
+	}
+	ip = append([]byte(nil), ip...)
+	port := uint16(addr.Port)
+
+	if t == AttrXORMappedAddress {
+		port ^= uint16(MagicCookie >> 16)
+		key := make([]byte, 16)
+		binary.BigEndian.PutUint32(key[0:4], MagicCookie)
+		copy(key[4:], m.TransactionID[:])
+		for i := range ip {
+			ip[i] ^= key[i]
+		}
+	}
+
+	val := make([]byte, 4+len(ip))
+	val[1] = family
+	binary.BigEndian.PutUint16(val[2:4], port)
+	copy(val[4:], ip)
+	m.Add(t, val)
+}
+
+// MappedAddress returns the reflexive address from a binding response,
+// preferring XOR-MAPPED-ADDRESS over the legacy MAPPED-ADDRESS
+func (m *Message) MappedAddress() (*net.UDPAddr, error) {
+	if addr, ok := m.Address(AttrXORMappedAddress); ok {
+		return addr, nil
+	}
+	if addr, ok := m.Address(AttrMappedAddress); ok {
+		return addr, nil
+	}
+	return nil, ErrNoAddress
+}
+
+// OtherAddress returns the server's alternate address from a binding response,
+// falling back to the RFC 3489 CHANGED-ADDRESS
+func (m *Message) OtherAddress() (*net.UDPAddr, bool) {
+	if addr, ok := m.Address(AttrOtherAddress); ok {
+		return addr, true
+	}
+	return m.Address(AttrChangedAddress)
+}
+
+// Error returns the error code and reason of a binding error response
+func (m *Message) Error() error {
+	val, ok := m.Get(AttrErrorCode)
+	if !ok || len(val) < 4 {
+		return fmt.Errorf("binding error without an error code")
+	}
+	code := int(val[2]&0x07)*100 + int(val[3])
+	return fmt.Errorf("binding error %d: %s", code, string(val[4:]))
+}
+
+func padded(l int) int {
+	return (l + 3) &^ 3
+}
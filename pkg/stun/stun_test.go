@@ -0,0 +1,78 @@
+package stun
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	m, err := NewBindingRequest(ChangeIP | ChangePort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Type = TypeBindingResponse
+	mapped := &net.UDPAddr{IP: net.IPv4(100, 88, 12, 7), Port: 54321}
+	m.AddAddress(AttrXORMappedAddress, mapped)
+	m.Add(AttrSoftware, []byte("gomo"))
+
+	parsed, err := Parse(m.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.TransactionID != m.TransactionID || parsed.Type != TypeBindingResponse {
+		t.Fatalf("Expected header to round trip but got %+v", parsed)
+	}
+
+	got, err := parsed.MappedAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.IP.Equal(mapped.IP) || got.Port != mapped.Port {
+		t.Fatalf("Expected mapped address %s but got %s", mapped, got)
+	}
+
+	// the raw attribute must not contain the address in the clear
+	raw, _ := parsed.Get(AttrXORMappedAddress)
+	if net.IP(raw[4:8]).Equal(mapped.IP) {
+		t.Fatal("Expected XOR-MAPPED-ADDRESS to be obfuscated")
+	}
+
+	if _, err := Parse([]byte("GET / HTTP/1.1\r\n\r\n")); err != ErrNotSTUN {
+		t.Fatalf("Expected ErrNotSTUN but got %v", err)
+	}
+}
+
+func TestBinding(t *testing.T) {
+	server, err := ListenServer(net.IPv4(127, 0, 0, 1), net.IPv4(127, 0, 0, 2))
+	if err != nil {
+		t.Skipf("Could not listen on two loopback addresses: %v", err)
+	}
+	defer server.Close()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := NewClient(conn)
+
+	resp, err := c.Binding(context.Background(), server.Primary, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Mapped.String() != conn.LocalAddr().String() {
+		t.Fatalf("Expected mapped address %s but got %s", conn.LocalAddr(), resp.Mapped)
+	}
+	if resp.Other == nil || resp.Other.String() != server.Other.String() {
+		t.Fatalf("Expected other address %s but got %v", server.Other, resp.Other)
+	}
+
+	resp, err = c.Binding(context.Background(), server.Primary, ChangeIP|ChangePort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Source.String() != server.Other.String() {
+		t.Fatalf("Expected response from %s but got %s", server.Other, resp.Source)
+	}
+}
@@ -56,10 +56,13 @@ func NewTrashcan(hostname string, timeout time.Duration) (*Trashcan, error) {
 func (t *Trashcan) FetchRadioStatusAsync(wg *sync.WaitGroup, ret chan<- *models.FastmileReturn) {
 	defer wg.Done()
 
+	start := time.Now()
 	data, err := t.FetchRadioStatus()
 	response := &models.FastmileReturn{
-		Body:  data,
-		Error: err,
+		Body:     data,
+		Error:    err,
+		Start:    start,
+		Duration: time.Since(start),
 	}
 
 	ret <- response